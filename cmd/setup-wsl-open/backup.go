@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Backups are stored next to the original file, like
+// mimeapps.list.wsl-open-proxy.bak-20240101T123456
+const backupInfix = ".wsl-open-proxy.bak-"
+
+const backupTimeFormat = "20060102T150405"
+
+// backupFile saves the original content of the file before it is modified.
+func backupFile(filePath string, content []byte, now time.Time) (string, error) {
+	backupPath := filePath + backupInfix + now.Format(backupTimeFormat)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(backupPath); err != nil && os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", errors.Wrapf(err, "failed to check existence of %s", backupPath)
+		}
+		backupPath = fmt.Sprintf("%s%s%s-%d", filePath, backupInfix, now.Format(backupTimeFormat), i)
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := atomicWriteFile(backupPath, content, perm); err != nil {
+		return "", errors.Wrapf(err, "failed to back up %s", filePath)
+	}
+	return backupPath, nil
+}
+
+type backup struct {
+	path     string
+	original string
+	time     time.Time
+	// Suffix added to backups made in the same second; 1 for the first one
+	seq int
+}
+
+// parseBackupPath extracts the original file and the time from the path of
+// a backup.
+func parseBackupPath(backupPath string) (backup, bool) {
+	idx := strings.LastIndex(backupPath, backupInfix)
+	if idx < 0 {
+		return backup{}, false
+	}
+	timeText := backupPath[idx+len(backupInfix):]
+	seq := 1
+	if before, seqText, ok := strings.Cut(timeText, "-"); ok {
+		n, err := strconv.Atoi(seqText)
+		if err != nil || n < 2 {
+			return backup{}, false
+		}
+		timeText, seq = before, n
+	}
+	backupTime, err := time.ParseInLocation(backupTimeFormat, timeText, time.Local)
+	if err != nil {
+		return backup{}, false
+	}
+	return backup{
+		path:     backupPath,
+		original: backupPath[:idx],
+		time:     backupTime,
+		seq:      seq,
+	}, true
+}
+
+// compareBackups orders backups from the oldest to the newest.
+func compareBackups(a, b backup) int {
+	if c := a.time.Compare(b.time); c != 0 {
+		return c
+	}
+	if c := a.seq - b.seq; c != 0 {
+		return c
+	}
+	return strings.Compare(a.path, b.path)
+}
+
+// listBackups lists the backups of the files managed by setup-wsl-open,
+// from the oldest to the newest.
+func listBackups() ([]backup, error) {
+	patterns := []string{
+		// Including the desktop-specific ones like gnome-mimeapps.list
+		path.Join(xdg.ConfigHome, "*mimeapps.list"+backupInfix+"*"),
+		path.Join(xdg.DataHome, "applications", "wsl-open-proxy-*.desktop"+backupInfix+"*"),
+	}
+	var backups []backup
+	for _, pattern := range patterns {
+		backupPaths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list backups")
+		}
+		for _, backupPath := range backupPaths {
+			if b, ok := parseBackupPath(backupPath); ok {
+				backups = append(backups, b)
+			}
+		}
+	}
+	slices.SortFunc(backups, compareBackups)
+	return backups, nil
+}
+
+func newRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [backup-or-original-file...]",
+		Short: "List or restore backups made by setup-wsl-open",
+		Long: "Without arguments, lists the backups made by setup-wsl-open.\n" +
+			"Given backup files, restores them to the original location.\n" +
+			"Given original files (like ~/.config/mimeapps.list), restores their latest backups.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, err := fileWriterFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			if len(args) == 0 {
+				return printBackups()
+			}
+			return restore(w, args)
+		},
+	}
+	addFileWriterFlags(cmd)
+	return cmd
+}
+
+func printBackups() error {
+	backups, err := listBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(os.Stderr, "No backups found\n")
+		return nil
+	}
+	for _, b := range backups {
+		fmt.Printf("%s\t%s\t%s\n", b.time.Format(time.DateTime), b.original, b.path)
+	}
+	return nil
+}
+
+func restore(w *fileWriter, files []string) error {
+	for _, file := range files {
+		b, ok := parseBackupPath(file)
+		if !ok {
+			latest, err := latestBackup(file)
+			if err != nil {
+				return err
+			}
+			b = latest
+		}
+		content, err := os.ReadFile(b.path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", b.path)
+		}
+		fmt.Fprintf(os.Stderr, "Restoring %s from %s...\n", b.original, b.path)
+		if err := w.writeFileWithConfirmation(b.original, content); err != nil {
+			return err
+		}
+	}
+	return w.finish()
+}
+
+func latestBackup(original string) (backup, error) {
+	original, err := filepath.Abs(original)
+	if err != nil {
+		return backup{}, err
+	}
+	backupPaths, err := filepath.Glob(original + backupInfix + "*")
+	if err != nil {
+		return backup{}, errors.Wrap(err, "failed to list backups")
+	}
+	var latest backup
+	found := false
+	for _, backupPath := range backupPaths {
+		b, ok := parseBackupPath(backupPath)
+		if ok && (!found || compareBackups(b, latest) > 0) {
+			latest = b
+			found = true
+		}
+	}
+	if !found {
+		return backup{}, errors.Errorf("No backup found for %s", original)
+	}
+	return latest, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteFileKeepsBackup(t *testing.T) {
+	home := setupXDG(t)
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "old\n")
+	if err := os.Chmod(mimeAppsListPath, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &fileWriter{yes: true}
+	if err := w.writeFileWithConfirmation(mimeAppsListPath, []byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	backups, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("len(backups) = %d, want 1", len(backups))
+	}
+	if backups[0].original != mimeAppsListPath {
+		t.Errorf("backups[0].original = %q, want %q", backups[0].original, mimeAppsListPath)
+	}
+	content, err := os.ReadFile(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "old\n" {
+		t.Errorf("backup content = %q, want %q", content, "old\n")
+	}
+
+	if err := restore(w, []string{mimeAppsListPath}); err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "old\n" {
+		t.Errorf("restored content = %q, want %q", content, "old\n")
+	}
+}
+
+func TestAtomicWriteFileFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := path.Join(dir, "dotfiles/mimeapps.list")
+	link := path.Join(dir, "mimeapps.list")
+	writeTestFile(t, target, "old\n")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := atomicWriteFile(link, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Readlink(link); err != nil {
+		t.Errorf("symlink is replaced: %v", err)
+	}
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "new\n" {
+		t.Errorf("content = %q, want %q", content, "new\n")
+	}
+}
+
+func TestParseBackupPath(t *testing.T) {
+	b, ok := parseBackupPath("/home/user/.config/mimeapps.list.wsl-open-proxy.bak-20240102T030405-2")
+	if !ok {
+		t.Fatal("parseBackupPath() failed")
+	}
+	want := backup{
+		path:     "/home/user/.config/mimeapps.list.wsl-open-proxy.bak-20240102T030405-2",
+		original: "/home/user/.config/mimeapps.list",
+		time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local),
+		seq:      2,
+	}
+	if diff := cmp.Diff(want, b, cmp.AllowUnexported(backup{})); diff != "" {
+		t.Errorf("parseBackupPath() mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := parseBackupPath("/home/user/.config/mimeapps.list"); ok {
+		t.Error("parseBackupPath() succeeded for a non-backup path")
+	}
+}
+
+func TestListBackupsOrder(t *testing.T) {
+	home := setupXDG(t)
+	gnomeMimeAppsListPath := path.Join(home, ".config/gnome-mimeapps.list")
+	var want []string
+	for _, suffix := range []string{"20240102T030405", "20240102T030405-2", "20240102T030405-10"} {
+		backupPath := gnomeMimeAppsListPath + backupInfix + suffix
+		writeTestFile(t, backupPath, suffix+"\n")
+		want = append(want, backupPath)
+	}
+
+	backups, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range backups {
+		got = append(got, b.path)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("listBackups() mismatch (-want +got):\n%s", diff)
+	}
+
+	latest, err := latestBackup(gnomeMimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.path != want[2] {
+		t.Errorf("latestBackup() = %q, want %q", latest.path, want[2])
+	}
+}
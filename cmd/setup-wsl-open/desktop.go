@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+var desktopNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// targetMimeAppsListPath returns the mimeapps.list to register the
+// associations in: $XDG_CONFIG_HOME/$desktop-mimeapps.list if the desktop is
+// given, and $XDG_CONFIG_HOME/mimeapps.list otherwise.
+func targetMimeAppsListPath(desktop string) (string, error) {
+	if desktop == "" {
+		return mimeAppsListPath(), nil
+	}
+	if !desktopNamePattern.MatchString(desktop) {
+		return "", errors.Errorf("Invalid desktop name: %s", desktop)
+	}
+	return path.Join(xdg.ConfigHome, strings.ToLower(desktop)+"-mimeapps.list"), nil
+}
+
+// shadowingEntry is a default application in a mimeapps.list that takes
+// precedence over the one setup-wsl-open registered.
+type shadowingEntry struct {
+	path     string
+	mimeType string
+	value    string
+}
+
+// findShadowingEntries looks for default applications for the MIME types
+// in the mimeapps.list files that are looked up before the target. The
+// boolean is false if the target is not looked up at all in the current
+// desktop environment.
+func findShadowingEntries(target string, desktopID string, mimeTypes []string) ([]shadowingEntry, bool, error) {
+	paths := mimeAppsListPaths()
+	targetIdx := slices.Index(paths, target)
+	if targetIdx < 0 {
+		return nil, false, nil
+	}
+	var entries []shadowingEntry
+	for _, mimeAppsListPath := range paths[:targetIdx] {
+		text, err := os.ReadFile(mimeAppsListPath)
+		if err != nil && os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, true, errors.Wrapf(err, "failed to read %s", mimeAppsListPath)
+		}
+		mimeAppsList := xdgini.ParseConfig(string(text))
+		for _, mimeType := range mimeTypes {
+			value, ok := defaultApplicationsValue(mimeAppsList, mimeType)
+			if !ok || slices.Index(xdgini.SplitList(value), desktopID) == 0 {
+				continue
+			}
+			entries = append(entries, shadowingEntry{mimeAppsListPath, mimeType, value})
+		}
+	}
+	return entries, true, nil
+}
+
+// reportShadowingEntries warns about the default applications that would be
+// used instead of the ones registered in the target.
+func reportShadowingEntries(target string, mimeEntries []mimeEntry) error {
+	for _, mimeEntry := range mimeEntries {
+		entries, used, err := findShadowingEntries(target, desktopEntryID(mimeEntry), mimeEntry.mimeTypes)
+		if err != nil {
+			return err
+		}
+		if !used {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not used in the current desktop environment (XDG_CURRENT_DESKTOP=%s)\n", target, os.Getenv("XDG_CURRENT_DESKTOP"))
+			return nil
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(os.Stderr, "Warning: %s is associated with %s in %s, which takes precedence over %s\n", entry.mimeType, entry.value, entry.path, target)
+			if desktop, ok := desktopOfMimeAppsList(entry.path); ok {
+				fmt.Fprintf(os.Stderr, "  Rerun with --desktop %s to register in %s\n", desktop, entry.path)
+			}
+		}
+	}
+	return nil
+}
+
+// desktopOfMimeAppsList returns the desktop name if the path is a
+// desktop-specific mimeapps.list in $XDG_CONFIG_HOME.
+func desktopOfMimeAppsList(filePath string) (string, bool) {
+	if path.Dir(filePath) != xdg.ConfigHome {
+		return "", false
+	}
+	desktop, ok := strings.CutSuffix(path.Base(filePath), "-mimeapps.list")
+	return desktop, ok && desktop != ""
+}
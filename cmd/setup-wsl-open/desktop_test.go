@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindShadowingEntries(t *testing.T) {
+	home := setupXDG(t)
+	t.Setenv("XDG_CURRENT_DESKTOP", "ubuntu:GNOME")
+	writeTestFile(t, path.Join(home, ".config/gnome-mimeapps.list"), "[Default Applications]\napplication/pdf=evince.desktop\ntext/html=wsl-open-proxy-pdf.desktop;firefox.desktop;\n")
+	// Looked up after mimeapps.list
+	writeTestFile(t, path.Join(home, "etc/xdg/mimeapps.list"), "[Default Applications]\napplication/x-pdf=okular.desktop\n")
+
+	target := path.Join(home, ".config/mimeapps.list")
+	entries, used, err := findShadowingEntries(target, "wsl-open-proxy-pdf.desktop", []string{"application/pdf", "application/x-pdf", "text/html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !used {
+		t.Fatal("used = false, want true")
+	}
+	want := []shadowingEntry{
+		{path.Join(home, ".config/gnome-mimeapps.list"), "application/pdf", "evince.desktop"},
+	}
+	if diff := cmp.Diff(want, entries, cmp.AllowUnexported(shadowingEntry{})); diff != "" {
+		t.Errorf("findShadowingEntries() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The desktop-specific file is looked up first
+	target = path.Join(home, ".config/gnome-mimeapps.list")
+	entries, used, err = findShadowingEntries(target, "wsl-open-proxy-pdf.desktop", []string{"application/pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !used || len(entries) != 0 {
+		t.Errorf("findShadowingEntries() = %v, %v, want no entries", entries, used)
+	}
+
+	// Files for other desktops are not looked up at all
+	target = path.Join(home, ".config/kde-mimeapps.list")
+	_, used, err = findShadowingEntries(target, "wsl-open-proxy-pdf.desktop", []string{"application/pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used {
+		t.Errorf("used = true, want false")
+	}
+}
+
+func TestInstallForDesktop(t *testing.T) {
+	home := setupXDG(t)
+	t.Setenv("XDG_CURRENT_DESKTOP", "GNOME")
+	writeTestFile(t, exeInstallPath(), "")
+	gnomeMimeAppsListPath := path.Join(home, ".config/gnome-mimeapps.list")
+	writeTestFile(t, gnomeMimeAppsListPath, "[Default Applications]\napplication/pdf=evince.desktop\n")
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, "GNOME"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(gnomeMimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[Default Applications]\napplication/pdf=wsl-open-proxy-pdf.desktop\n", string(content)); diff != "" {
+		t.Errorf("gnome-mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(path.Join(home, ".config/mimeapps.list")); !os.IsNotExist(err) {
+		t.Errorf("mimeapps.list is created: %v", err)
+	}
+
+	// Uninstallation finds the file from the manifest
+	if err := uninstall(context.Background(), w, mimeEntries, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(gnomeMimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[Default Applications]\napplication/pdf=evince.desktop\n", string(content)); diff != "" {
+		t.Errorf("gnome-mimeapps.list mismatch after uninstallation (-want +got):\n%s", diff)
+	}
+}
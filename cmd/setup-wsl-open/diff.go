@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	diffFormatUnified = "unified"
+	diffFormatWord    = "word"
+	diffFormatColor   = "color"
+)
+
+var diffFormats = []string{diffFormatUnified, diffFormatWord, diffFormatColor}
+
+// Number of unchanged lines shown around changes in unified diffs
+const diffContextLines = 3
+
+// formatDiff renders the change from oldText to newText in the given format.
+// An empty oldName means the file is newly created.
+func formatDiff(format string, oldName string, newName string, oldText string, newText string) string {
+	switch format {
+	case diffFormatColor:
+		dmp := diffmatchpatch.New()
+		return dmp.DiffPrettyText(dmp.DiffMain(oldText, newText, false)) + "\n"
+	case diffFormatWord:
+		return wordDiff(oldText, newText)
+	default:
+		return unifiedDiff(oldName, newName, oldText, newText)
+	}
+}
+
+// wordDiff renders a character-level diff in plain text, using the markers
+// of `git diff --word-diff=plain`.
+func wordDiff(oldText string, newText string) string {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(oldText, newText, false))
+	var buf strings.Builder
+	for _, diff := range diffs {
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			buf.WriteString("{+" + diff.Text + "+}")
+		case diffmatchpatch.DiffDelete:
+			buf.WriteString("[-" + diff.Text + "-]")
+		case diffmatchpatch.DiffEqual:
+			buf.WriteString(diff.Text)
+		}
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+type diffLine struct {
+	// One of ' ', '-' and '+'
+	op   byte
+	text string
+}
+
+// unifiedDiff renders a line-based diff in the unified format.
+// An empty oldName means the file is newly created.
+func unifiedDiff(oldName string, newName string, oldText string, newText string) string {
+	lines := diffLines(oldText, newText)
+
+	var buf strings.Builder
+	if oldName == "" {
+		oldName = "/dev/null"
+	}
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Line numbers (0-based) just before lines[i]
+	oldLineNos := make([]int, len(lines)+1)
+	newLineNos := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldLineNos[i+1] = oldLineNos[i]
+		newLineNos[i+1] = newLineNos[i]
+		if line.op != '+' {
+			oldLineNos[i+1]++
+		}
+		if line.op != '-' {
+			newLineNos[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(lines) {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is close enough
+		start := max(0, i-diffContextLines)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContextLines {
+				break
+			}
+		}
+		end = min(len(lines), end+diffContextLines)
+
+		fmt.Fprintf(
+			&buf,
+			"@@ -%s +%s @@\n",
+			hunkRange(oldLineNos[start], oldLineNos[end]-oldLineNos[start]),
+			hunkRange(newLineNos[start], newLineNos[end]-newLineNos[start]),
+		)
+		for _, line := range lines[start:end] {
+			buf.WriteByte(line.op)
+			buf.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+func hunkRange(before int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	} else if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func diffLines(oldText string, newText string) []diffLine {
+	// Map each distinct line to a rune so that diffmatchpatch compares lines.
+	// (DiffLinesToChars is not used as it is broken for more than 10 lines
+	// in the version we depend on.)
+	var lineArray []string
+	lineIndices := map[string]rune{}
+	toRunes := func(text string) []rune {
+		var runes []rune
+		for text != "" {
+			lineEnd := strings.IndexByte(text, '\n') + 1
+			if lineEnd == 0 {
+				lineEnd = len(text)
+			}
+			line := text[:lineEnd]
+			text = text[lineEnd:]
+			index, ok := lineIndices[line]
+			if !ok {
+				// Skip surrogates, which cannot be represented in strings
+				index = rune(len(lineArray))
+				if index >= 0xD800 {
+					index += 0x800
+				}
+				lineIndices[line] = index
+				lineArray = append(lineArray, line)
+			}
+			runes = append(runes, index)
+		}
+		return runes
+	}
+	oldRunes := toRunes(oldText)
+	newRunes := toRunes(newText)
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffMainRunes(oldRunes, newRunes, false)
+
+	var lines []diffLine
+	for _, diff := range diffs {
+		op := byte(' ')
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		}
+		for _, index := range diff.Text {
+			if index >= 0xD800 {
+				index -= 0x800
+			}
+			lines = append(lines, diffLine{op: op, text: lineArray[index]})
+		}
+	}
+	return lines
+}
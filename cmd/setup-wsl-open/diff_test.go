@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testcases := []struct {
+		name    string
+		oldName string
+		oldText string
+		newText string
+		output  string
+	}{
+		{
+			name:    "new file",
+			oldName: "",
+			oldText: "",
+			newText: "[Foo]\nKey1=Value1\n",
+			output:  "--- /dev/null\n+++ new\n@@ -0,0 +1,2 @@\n+[Foo]\n+Key1=Value1\n",
+		},
+		{
+			name:    "replace a line",
+			oldName: "old",
+			oldText: "[Foo]\nKey1=Value1\nKey2=Value2\n",
+			newText: "[Foo]\nKey1=Value3\nKey2=Value2\n",
+			output:  "--- old\n+++ new\n@@ -1,3 +1,3 @@\n [Foo]\n-Key1=Value1\n+Key1=Value3\n Key2=Value2\n",
+		},
+		{
+			name:    "context",
+			oldName: "old",
+			oldText: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n",
+			newText: "1\n2\n3\n4\n5\nfive\n7\n8\n9\n10\n11\n12\n13\n14\nfourteen\n16\n",
+			output:  "--- old\n+++ new\n@@ -3,7 +3,7 @@\n 3\n 4\n 5\n-6\n+five\n 7\n 8\n 9\n@@ -12,5 +12,5 @@\n 12\n 13\n 14\n-15\n+fourteen\n 16\n",
+		},
+		{
+			name:    "merged hunks",
+			oldName: "old",
+			oldText: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			newText: "one\n2\n3\n4\n5\n6\n7\neight\n",
+			output:  "--- old\n+++ new\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+		{
+			name:    "without last newline",
+			oldName: "old",
+			oldText: "[Foo]\nKey1=Value1",
+			newText: "[Foo]\nKey1=Value1\nKey2=Value2\n",
+			output:  "--- old\n+++ new\n@@ -1,2 +1,3 @@\n [Foo]\n-Key1=Value1\n\\ No newline at end of file\n+Key1=Value1\n+Key2=Value2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := unifiedDiff(tc.oldName, "new", tc.oldText, tc.newText)
+			if diff := cmp.Diff(tc.output, output); diff != "" {
+				t.Errorf("unifiedDiff() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	output := wordDiff("text/html=firefox.desktop\n", "text/html=wsl-open-proxy-html.desktop\n")
+	want := "text/html=[-firefox-]{+wsl-open-proxy-html+}.desktop\n"
+	if diff := cmp.Diff(want, output); diff != "" {
+		t.Errorf("wordDiff() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+	"golang.org/x/term"
+)
+
+// fileWriter applies changes to files, asking for confirmation before
+// overwriting existing ones.
+type fileWriter struct {
+	// Apply changes without asking
+	yes bool
+	// Only report changes without applying them
+	dryRun bool
+	// Whether we can ask the user via stdin
+	interactive bool
+	// One of diffFormats
+	diffFormat string
+	// Whether any change has been applied (or would be in dry-run mode)
+	changed bool
+}
+
+// newFileWriter creates a fileWriter; an empty diffFormat selects colored
+// output for terminals and unified diffs otherwise.
+func newFileWriter(yes bool, dryRun bool, diffFormat string) *fileWriter {
+	if diffFormat == "" {
+		diffFormat = diffFormatUnified
+		if colored(os.Stderr) {
+			diffFormat = diffFormatColor
+		}
+	}
+	return &fileWriter{
+		yes:         yes,
+		dryRun:      dryRun,
+		interactive: term.IsTerminal(int(os.Stdin.Fd())),
+		diffFormat:  diffFormat,
+	}
+}
+
+func (w *fileWriter) writeFileWithConfirmation(filePath string, data []byte) error {
+	return w.writeFile(filePath, data, true)
+}
+
+// writeFile writes the data to the file, showing the changes if the file
+// already exists. If needsConfirmation is false, the changes are applied
+// without asking.
+func (w *fileWriter) writeFile(filePath string, data []byte, needsConfirmation bool) error {
+	oldContent, err := os.ReadFile(filePath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", filePath)
+	} else if exists {
+		if string(oldContent) == string(data) {
+			// No need to update
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Need to apply the following changes to %s:\n", filePath)
+		w.printDiff(filePath, string(oldContent), string(data))
+		if needsConfirmation {
+			if err := w.confirm(fmt.Sprintf("Overwrite to %s", filePath)); err != nil {
+				return err
+			}
+		}
+	} else if w.dryRun {
+		fmt.Fprintf(os.Stderr, "Need to create %s with the following content:\n", filePath)
+		w.printDiff(filePath, "", string(data))
+	}
+
+	w.changed = true
+	if w.dryRun {
+		return nil
+	}
+	if exists {
+		backupPath, err := backupFile(filePath, oldContent, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Backed up the original %s to %s\n", filePath, backupPath)
+	}
+	if err := atomicWriteFile(filePath, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", filePath)
+	}
+	return nil
+}
+
+// writeGeneratedFile replaces a file that is generated from other files,
+// like mimeinfo.cache. It is written without confirmation or backups.
+func (w *fileWriter) writeGeneratedFile(filePath string, data []byte) error {
+	oldContent, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", filePath)
+	} else if err == nil && string(oldContent) == string(data) {
+		// No need to update
+		return nil
+	}
+	w.changed = true
+	if w.dryRun {
+		fmt.Fprintf(os.Stderr, "Need to regenerate %s\n", filePath)
+		return nil
+	}
+	if err := atomicWriteFile(filePath, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", filePath)
+	}
+	fmt.Fprintf(os.Stderr, "Regenerated %s\n", filePath)
+	return nil
+}
+
+// atomicWriteFile replaces the file with the data so that the file is
+// never observed partially written, even if the process is interrupted.
+// The mode of the existing file is preserved, and symbolic links are
+// followed so that the link itself is kept.
+func atomicWriteFile(filePath string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// No-op once renamed
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	dirFile, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer dirFile.Close()
+	return dirFile.Sync()
+}
+
+func (w *fileWriter) removeFile(filePath string) error {
+	if _, err := os.Stat(filePath); err != nil && os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to check existence of %s", filePath)
+	}
+	w.changed = true
+	if w.dryRun {
+		fmt.Fprintf(os.Stderr, "Need to remove %s\n", filePath)
+		return nil
+	}
+	if err := os.Remove(filePath); err != nil {
+		return errors.Wrapf(err, "failed to remove %s", filePath)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %s\n", filePath)
+	return nil
+}
+
+// confirm asks the user whether to proceed with the action.
+func (w *fileWriter) confirm(action string) error {
+	if w.yes || w.dryRun {
+		return nil
+	}
+	if !w.interactive {
+		return errors.Errorf("%s needs confirmation, but stdin is not a terminal; rerun with --yes to apply or --dry-run to preview changes", action)
+	}
+	answer := prompt.Input("Apply this change? [y/N]", yesNoCompleter)
+	if answer != "y" && answer != "Y" {
+		return errors.Errorf("%s is canceled", action)
+	}
+	return nil
+}
+
+// finish reports the completion, or pending changes as an error in dry-run
+// mode.
+func (w *fileWriter) finish() error {
+	if !w.dryRun {
+		fmt.Fprintf(os.Stderr, "Done\n")
+	} else if w.changed {
+		return errors.New("Changes are needed (dry run)")
+	} else {
+		fmt.Fprintf(os.Stderr, "No changes needed\n")
+	}
+	return nil
+}
+
+// printDiff shows the change to the file. An empty oldContent means the
+// file is newly created.
+func (w *fileWriter) printDiff(filePath string, oldContent string, newContent string) {
+	oldName := filePath
+	if oldContent == "" {
+		oldName = ""
+	}
+	fmt.Fprint(os.Stderr, formatDiff(w.diffFormat, oldName, filePath, oldContent, newContent))
+}
+
+func yesNoCompleter(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{
+		{Text: "y", Description: "Apply this change"},
+		{Text: "n", Description: "Do not apply this change"},
+	}
+}
+
+func colored(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd())) && os.Getenv("NO_COLOR") == ""
+}
+
+// parseConfigFile parses an INI-like file like mimeapps.list, showing the
+// syntax errors in it before any change to the file is asked for. The file is
+// still parsed leniently so that the broken lines are kept as they are.
+func parseConfigFile(filePath string, text []byte) *xdgini.Config {
+	config, diagnostics := xdgini.ParseConfigStrict(string(text))
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", filePath, d)
+	}
+	return config
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFileWriter(t *testing.T) {
+	testcases := []struct {
+		name        string
+		yes         bool
+		dryRun      bool
+		interactive bool
+		wantErr     bool
+		wantContent string
+	}{
+		{
+			name:        "yes",
+			yes:         true,
+			wantContent: "new\n",
+		},
+		{
+			name:        "dry run",
+			dryRun:      true,
+			wantContent: "old\n",
+		},
+		{
+			name:        "non-interactive",
+			wantErr:     true,
+			wantContent: "old\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := path.Join(t.TempDir(), "mimeapps.list")
+			writeTestFile(t, filePath, "old\n")
+			w := &fileWriter{
+				yes:         tc.yes,
+				dryRun:      tc.dryRun,
+				interactive: tc.interactive,
+			}
+			err := w.writeFileWithConfirmation(filePath, []byte("new\n"))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("writeFileWithConfirmation() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tc.wantContent {
+				t.Errorf("content = %q, want %q", content, tc.wantContent)
+			}
+			if err := w.finish(); (err != nil) != tc.dryRun {
+				t.Errorf("finish() error = %v, want error: %v", err, tc.dryRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func userMediaGroupsPath() string {
+	return path.Join(xdg.ConfigHome, "wsl-open-proxy", "groups.ini")
+}
+
+// loadUserMediaGroups adds the media groups defined in groups.ini to
+// mediaGroups. A missing file is not an error. It is called by the commands
+// that need the media groups, so that a broken groups.ini does not prevent
+// the other commands from running.
+func loadUserMediaGroups() error {
+	groupsPath := userMediaGroupsPath()
+	text, err := os.ReadFile(groupsPath)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to read %s", groupsPath)
+	}
+	merged, err := mergeMediaGroups(mediaGroups, groupsPath, xdgini.ParseConfig(string(text)))
+	if err != nil {
+		return err
+	}
+	mediaGroups = merged
+	return nil
+}
+
+var (
+	mediaGroupNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	extensionPattern      = regexp.MustCompile(`^\.[^/\\\s]+$`)
+	// URL scheme followed by a colon, like "mailto:"
+	schemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:$`)
+)
+
+// groupsError lists all the problems found in a groups.ini file.
+type groupsError struct {
+	problems []groupsProblem
+}
+
+type groupsProblem struct {
+	lineNumber int
+	message    string
+}
+
+func (e *groupsError) Error() string {
+	messages := make([]string, 0, len(e.problems))
+	for _, problem := range e.problems {
+		messages = append(messages, problem.message)
+	}
+	return "invalid media group definitions:\n  " + strings.Join(messages, "\n  ")
+}
+
+// mergeMediaGroups returns a copy of base extended with the media groups
+// defined in the config. Each group in the config either defines a new media
+// group or adds extensions and MIME types to an existing one, like:
+//
+//	[office]
+//	.docx=application/vnd.openxmlformats-officedocument.wordprocessingml.document
+//	.xlsx=application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//
+// URL schemes are given with a trailing colon. Without MIME types, they are
+// registered for x-scheme-handler/<scheme>:
+//
+//	[scheme]
+//	slack:=
+func mergeMediaGroups(base map[string][]mimeEntry, fileName string, config *xdgini.Config) (map[string][]mimeEntry, error) {
+	merged := make(map[string][]mimeEntry, len(base))
+	for name, group := range base {
+		merged[name] = slices.Clone(group)
+	}
+
+	groupErr := &groupsError{}
+	report := func(raw *xdgini.RawLineStyle, format string, args ...any) {
+		groupErr.problems = append(groupErr.problems, groupsProblem{
+			lineNumber: raw.LineNumber,
+			message:    fmt.Sprintf("%s:%d: %s", fileName, raw.LineNumber, fmt.Sprintf(format, args...)),
+		})
+	}
+
+	type groupItem struct {
+		name  string
+		group *xdgini.ConfigGroup
+		raw   *xdgini.RawLineStyle
+	}
+	var groupItems []groupItem
+	for name, group := range config.Groups {
+		if len(group.Raws) == 0 {
+			continue
+		}
+		groupItems = append(groupItems, groupItem{name, group, group.Raws[0]})
+	}
+	slices.SortFunc(groupItems, func(a, b groupItem) int {
+		return a.raw.Order - b.raw.Order
+	})
+
+	for _, item := range groupItems {
+		for _, raw := range item.group.Raws {
+			if item.name == "" && raw.LineNumber == 0 {
+				// Dummy group for entries outside of any group
+				continue
+			}
+			tLine := strings.TrimSpace(raw.Line)
+			if !strings.HasSuffix(tLine, "]") {
+				report(raw, "broken group header %q", tLine)
+			} else if !mediaGroupNamePattern.MatchString(item.name) {
+				report(raw, "invalid media group name %q: only letters, digits, '.', '-' and '_' are allowed", item.name)
+			}
+		}
+
+		type entryItem struct {
+			extension string
+			entry     *xdgini.ConfigEntry
+		}
+		var entryItems []entryItem
+		for extension, entry := range item.group.Entries {
+			entryItems = append(entryItems, entryItem{extension, entry})
+		}
+		slices.SortFunc(entryItems, func(a, b entryItem) int {
+			return a.entry.Raws[0].Order - b.entry.Raws[0].Order
+		})
+
+		for _, entryItem := range entryItems {
+			raw := entryItem.entry.Raws[0]
+			for _, dupRaw := range entryItem.entry.Raws[1:] {
+				report(dupRaw, "duplicate extension %q (first defined at line %d)", entryItem.extension, raw.LineNumber)
+			}
+			if item.name == "" {
+				report(raw, "extension %q is defined outside of a media group", entryItem.extension)
+				continue
+			}
+			if !strings.Contains(raw.Line, "=") {
+				report(raw, "missing '=' after extension %q", entryItem.extension)
+				continue
+			}
+			isScheme := schemePattern.MatchString(entryItem.extension)
+			if !isScheme && !extensionPattern.MatchString(entryItem.extension) {
+				report(raw, "invalid extension %q: must start with '.' and not contain slashes or spaces", entryItem.extension)
+				continue
+			}
+			var mimeTypes []string
+			for _, mimeType := range strings.Split(entryItem.entry.Value, ";") {
+				mimeType = strings.TrimSpace(mimeType)
+				if mimeType == "" {
+					continue
+				}
+				if !xdgini.IsValidMIMEType(mimeType) {
+					report(raw, "invalid MIME type %q for extension %q", mimeType, entryItem.extension)
+					continue
+				}
+				mimeTypes = append(mimeTypes, mimeType)
+			}
+			if len(mimeTypes) == 0 && isScheme && strings.TrimSpace(entryItem.entry.Value) == "" {
+				mimeTypes = []string{"x-scheme-handler/" + strings.ToLower(strings.TrimSuffix(entryItem.extension, ":"))}
+			}
+			if len(mimeTypes) == 0 {
+				report(raw, "no MIME type given for extension %q", entryItem.extension)
+				continue
+			}
+			merged[item.name] = addMimeTypes(merged[item.name], entryItem.extension, mimeTypes)
+		}
+	}
+
+	if len(groupErr.problems) > 0 {
+		slices.SortStableFunc(groupErr.problems, func(a, b groupsProblem) int {
+			return a.lineNumber - b.lineNumber
+		})
+		return nil, groupErr
+	}
+	return merged, nil
+}
+
+// addMimeTypes adds the MIME types to the extension in the media group,
+// adding the extension if it does not exist yet.
+func addMimeTypes(group []mimeEntry, extension string, mimeTypes []string) []mimeEntry {
+	idx := slices.IndexFunc(group, func(e mimeEntry) bool {
+		return e.extension == extension
+	})
+	if idx < 0 {
+		return append(group, mimeEntry{extension, mimeTypes})
+	}
+	entry := mimeEntry{extension, slices.Clone(group[idx].mimeTypes)}
+	for _, mimeType := range mimeTypes {
+		if !slices.Contains(entry.mimeTypes, mimeType) {
+			entry.mimeTypes = append(entry.mimeTypes, mimeType)
+		}
+	}
+	group[idx] = entry
+	return group
+}
+
+// collectMimeEntries gathers the extensions of the media groups and the
+// additional extensions. MIME types of the same extension are merged.
+func collectMimeEntries(mediaGroupNames []string, extensions []string) ([]mimeEntry, error) {
+	mediaGroupNames, err := validateMediaGroupNames(mediaGroupNames)
+	if err != nil {
+		return nil, err
+	}
+	var mimeEntries []mimeEntry
+	for _, mediaGroupName := range mediaGroupNames {
+		for _, mimeEntry := range mediaGroups[mediaGroupName] {
+			mimeEntries = addMimeTypes(mimeEntries, mimeEntry.extension, mimeEntry.mimeTypes)
+		}
+	}
+	for _, extension := range extensions {
+		if !extensionPattern.MatchString(extension) {
+			return nil, errors.Errorf("Invalid extension %q: must start with '.' and not contain slashes or spaces", extension)
+		}
+		mimeEntries = addMimeTypes(mimeEntries, extension, nil)
+	}
+	if len(mimeEntries) == 0 {
+		return nil, errors.New("No media group or extension specified")
+	}
+	return mimeEntries, nil
+}
+
+// resolveMimeEntries collects the entries to install. The extensions are
+// looked up in shared-mime-info; with useMimeDB, the entries of the media
+// groups are also extended with the MIME types and aliases found there.
+func resolveMimeEntries(mediaGroupNames []string, extensions []string, useMimeDB bool) ([]mimeEntry, error) {
+	mimeEntries, err := collectMimeEntries(mediaGroupNames, extensions)
+	if err != nil {
+		return nil, err
+	}
+	if !useMimeDB && len(extensions) == 0 {
+		return mimeEntries, nil
+	}
+	db, err := sharedmime.Load(sharedmime.DefaultDirs())
+	if err != nil {
+		return nil, err
+	}
+	for i, mimeEntry := range mimeEntries {
+		if !useMimeDB && !slices.Contains(extensions, mimeEntry.extension) {
+			continue
+		}
+		mimeEntries[i], err = expandMimeEntry(mimeEntry, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mimeEntries, nil
+}
+
+// expandMimeEntry adds the MIME types and aliases that shared-mime-info
+// associates with the extension.
+func expandMimeEntry(entry mimeEntry, db *sharedmime.Database) (mimeEntry, error) {
+	if _, ok := entryScheme(entry); ok {
+		// shared-mime-info knows nothing about URL schemes
+		return entry, nil
+	}
+	expanded := addMimeTypes([]mimeEntry{entry}, entry.extension, db.ExpandExtension(entry.extension))[0]
+	if len(expanded.mimeTypes) == 0 {
+		return entry, errors.Errorf("No MIME type found for %s in shared-mime-info", entry.extension)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestMergeMediaGroups(t *testing.T) {
+	base := map[string][]mimeEntry{
+		"image": {
+			{".png", []string{"image/png"}},
+			{".bmp", []string{"image/bmp"}},
+		},
+	}
+	input := "# My groups\n" +
+		"[office]\n" +
+		".docx=application/vnd.openxmlformats-officedocument.wordprocessingml.document\n" +
+		".csv=text/csv;text/x-csv;\n" +
+		"\n" +
+		"[image]\n" +
+		".bmp=image/x-ms-bmp\n" +
+		".webp=image/webp\n"
+	merged, err := mergeMediaGroups(base, "groups.ini", xdgini.ParseConfig(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]mimeEntry{
+		"office": {
+			{".docx", []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}},
+			{".csv", []string{"text/csv", "text/x-csv"}},
+		},
+		"image": {
+			{".png", []string{"image/png"}},
+			{".bmp", []string{"image/bmp", "image/x-ms-bmp"}},
+			{".webp", []string{"image/webp"}},
+		},
+	}
+	if diff := cmp.Diff(want, merged, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+		t.Errorf("mergeMediaGroups() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"image/bmp"}, base["image"][1].mimeTypes); diff != "" {
+		t.Errorf("mergeMediaGroups() modified the base (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeMediaGroupsSchemes(t *testing.T) {
+	base := map[string][]mimeEntry{
+		"scheme": {
+			{"mailto:", []string{"x-scheme-handler/mailto"}},
+		},
+	}
+	input := "[scheme]\n" +
+		"Slack:=\n" +
+		"mailto:=x-scheme-handler/mailto;x-scheme-handler/x-mailto;\n"
+	merged, err := mergeMediaGroups(base, "groups.ini", xdgini.ParseConfig(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]mimeEntry{
+		"scheme": {
+			{"mailto:", []string{"x-scheme-handler/mailto", "x-scheme-handler/x-mailto"}},
+			{"Slack:", []string{"x-scheme-handler/slack"}},
+		},
+	}
+	if diff := cmp.Diff(want, merged, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+		t.Errorf("mergeMediaGroups() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeMediaGroupsErrors(t *testing.T) {
+	input := ".txt=text/plain\n" +
+		"[office\n" +
+		"[bad,name]\n" +
+		"[office]\n" +
+		"docx=application/msword\n" +
+		".doc\n" +
+		".xls=spreadsheet\n" +
+		".ppt=\n" +
+		".rtf=application/rtf\n" +
+		".rtf=text/rtf\n" +
+		"1password:=\n"
+	_, err := mergeMediaGroups(mediaGroups, "groups.ini", xdgini.ParseConfig(input))
+	if err == nil {
+		t.Fatal("mergeMediaGroups() succeeded, want error")
+	}
+	want := "invalid media group definitions:\n" +
+		"  groups.ini:1: extension \".txt\" is defined outside of a media group\n" +
+		"  groups.ini:2: broken group header \"[office\"\n" +
+		"  groups.ini:3: invalid media group name \"bad,name\": only letters, digits, '.', '-' and '_' are allowed\n" +
+		"  groups.ini:5: invalid extension \"docx\": must start with '.' and not contain slashes or spaces\n" +
+		"  groups.ini:6: missing '=' after extension \".doc\"\n" +
+		"  groups.ini:7: invalid MIME type \"spreadsheet\" for extension \".xls\"\n" +
+		"  groups.ini:7: no MIME type given for extension \".xls\"\n" +
+		"  groups.ini:8: no MIME type given for extension \".ppt\"\n" +
+		"  groups.ini:10: duplicate extension \".rtf\" (first defined at line 9)\n" +
+		"  groups.ini:11: invalid extension \"1password:\": must start with '.' and not contain slashes or spaces"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("mergeMediaGroups() error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpandMimeEntry(t *testing.T) {
+	db := &sharedmime.Database{
+		Globs: []sharedmime.Glob{
+			{Weight: 50, MimeType: "application/pdf", Pattern: "*.pdf"},
+			{Weight: 50, MimeType: "image/bmp", Pattern: "*.bmp"},
+			{Weight: 50, MimeType: "application/vnd.ms-visio.drawing.main+xml", Pattern: "*.vsdx"},
+		},
+		Aliases: map[string]string{
+			"application/x-pdf": "application/pdf",
+			"image/x-ms-bmp":    "image/bmp",
+		},
+	}
+	testcases := []struct {
+		entry mimeEntry
+		want  mimeEntry
+	}{
+		{mediaGroups["pdf"][0], mimeEntry{".pdf", []string{"application/pdf", "application/x-pdf"}}},
+		{mimeEntry{".vsdx", nil}, mimeEntry{".vsdx", []string{"application/vnd.ms-visio.drawing.main+xml"}}},
+		// shared-mime-info is not consulted for URL schemes
+		{mimeEntry{"mailto:", []string{"x-scheme-handler/mailto"}}, mimeEntry{"mailto:", []string{"x-scheme-handler/mailto"}}},
+	}
+	for _, tc := range testcases {
+		got, err := expandMimeEntry(tc.entry, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+			t.Errorf("expandMimeEntry(%q) mismatch (-want +got):\n%s", tc.entry.extension, diff)
+		}
+	}
+
+	if _, err := expandMimeEntry(mimeEntry{".unknown", nil}, db); err == nil {
+		t.Error("expandMimeEntry() succeeded for an unknown extension, want error")
+	}
+}
+
+func TestResolveMimeEntries(t *testing.T) {
+	home := setupXDG(t)
+	docxMimeType := "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	writeTestFile(t, path.Join(home, "usr/share/mime/globs2"), "50:image/png:*.png\n50:"+docxMimeType+":*.docx\n")
+	writeTestFile(t, path.Join(home, "usr/share/mime/aliases"), "image/x-png image/png\n")
+
+	// Like -t image --ext .docx; the media group is left as is
+	mimeEntries, err := resolveMimeEntries([]string{"image"}, []string{".docx"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(slices.Clone(mediaGroups["image"]), mimeEntry{".docx", []string{docxMimeType}})
+	if diff := cmp.Diff(want, mimeEntries, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+		t.Errorf("resolveMimeEntries() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Like -t image --ext .docx --mime-db
+	mimeEntries, err = resolveMimeEntries([]string{"image"}, []string{".docx"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mimeEntries[0].mimeTypes, []string{"image/png", "image/x-png"}; !slices.Equal(got, want) {
+		t.Errorf("MIME types for .png = %v, want %v", got, want)
+	}
+}
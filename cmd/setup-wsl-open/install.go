@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	wslopenproxy "github.com/qnighy/wsl-open-proxy"
+	"github.com/qnighy/wsl-open-proxy/mimeapps"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+const (
+	// Register as the default application in "Default Applications"
+	associationModeDefault = "default"
+	// Register as an alternative in "Added Associations"
+	associationModeAdded = "added"
+)
+
+var associationModes = []string{associationModeDefault, associationModeAdded}
+
+func install(ctx context.Context, w *fileWriter, updateBin bool, mimeEntries []mimeEntry, mode string, desktop string) error {
+	_ = ctx
+
+	m, err := loadManifest()
+	if err != nil {
+		return err
+	}
+
+	exeInstallPath := exeInstallPath()
+	installBin := updateBin
+	_, err = os.Stat(exeInstallPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to check existence of wsl-open-proxy.exe")
+	} else if err != nil && os.IsNotExist(err) {
+		installBin = true
+	}
+
+	if installBin && w.dryRun {
+		fmt.Fprintf(os.Stderr, "Need to install wsl-open-proxy.exe to %s\n", exeInstallPath)
+		w.changed = true
+	} else if installBin {
+		exeFile, err := assets.ReadFile(fmt.Sprintf("assets/wsl-open-proxy-%s.exe", runtime.GOARCH))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to read wsl-open-proxy.exe in assets")
+		} else if err != nil && os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Building wsl-open-proxy.exe from source...\n")
+			// Not found; alternative installation
+			cmd := exec.Command("go", "install", fmt.Sprintf("github.com/qnighy/wsl-open-proxy/cmd/wsl-open-proxy@v%s", wslopenproxy.Version))
+			cmd.Env = append(os.Environ(), "GOOS=windows", fmt.Sprintf("GOARCH=%s", runtime.GOARCH))
+			_, err := cmd.Output()
+			if err != nil {
+				return errors.Wrap(err, "failed to build wsl-open-proxy.exe from source")
+			}
+
+			fmt.Fprintf(os.Stderr, "Installing wsl-open-proxy.exe built from source...\n")
+			gobin := os.Getenv("GOBIN")
+			if gobin == "" {
+				gopath := os.Getenv("GOPATH")
+				if gopath != "" {
+					gobin = path.Join(os.Getenv("GOPATH"), "bin")
+				} else {
+					gobin = path.Join(os.Getenv("HOME"), "go", "bin")
+				}
+			}
+			gobinSuffixed := path.Join(gobin, fmt.Sprintf("windows_%s", runtime.GOARCH))
+			exeBuiltPath := path.Join(gobinSuffixed, "wsl-open-proxy.exe")
+			if err := os.Rename(exeBuiltPath, exeInstallPath); err != nil {
+				return errors.Wrap(err, "failed to move wsl-open-proxy.exe")
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Installing prebuilt wsl-open-proxy.exe...\n")
+			if err := atomicWriteFile(exeInstallPath, exeFile, 0755); err != nil {
+				return errors.Wrap(err, "failed to write wsl-open-proxy.exe")
+			}
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "wsl-open-proxy.exe is already installed\n")
+	}
+	if !w.dryRun {
+		exeHash, err := fileSHA256(exeInstallPath)
+		if err != nil {
+			return err
+		}
+		if installBin {
+			m.Exe = &manifestExe{Path: exeInstallPath, Version: wslopenproxy.Version, SHA256: exeHash}
+		} else if m.Exe == nil || m.Exe.Path != exeInstallPath || m.Exe.SHA256 != exeHash {
+			// Installed by someone else, or by an older setup-wsl-open
+			m.Exe = &manifestExe{Path: exeInstallPath, SHA256: exeHash}
+		}
+	}
+
+	extensions := make([]string, 0, len(mimeEntries))
+	for _, mimeEntry := range mimeEntries {
+		extensions = append(extensions, mimeEntry.extension)
+	}
+	fmt.Fprintf(os.Stderr, "Registering desktop entries for %s files...\n", strings.Join(extensions, ", "))
+	for _, mimeEntry := range mimeEntries {
+		desktopEntryPath := desktopEntryPath(mimeEntry)
+		data := []byte(desktopEntryFor(mimeEntry).String())
+		// Files left untouched since we wrote them can be updated without asking
+		currentHash, err := fileSHA256(desktopEntryPath)
+		if err != nil {
+			return err
+		}
+		recorded := m.desktopEntry(desktopEntryPath)
+		owned := recorded != nil && recorded.SHA256 == currentHash
+		if err := w.writeFile(desktopEntryPath, data, !owned); err != nil {
+			return errors.Wrap(err, "failed to write application config")
+		}
+		m.recordDesktopEntry(manifestDesktopEntry{
+			Path:      desktopEntryPath,
+			Extension: mimeEntry.extension,
+			MimeTypes: mimeEntry.mimeTypes,
+			SHA256:    sha256Hex(data),
+		})
+	}
+	if err := m.save(w); err != nil {
+		return err
+	}
+	if err := updateMimeInfoCache(w); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Registering mime associations...\n")
+	mimeAppsListPath, err := targetMimeAppsListPath(desktop)
+	if err != nil {
+		return err
+	}
+	mimeAppsListText, err := os.ReadFile(mimeAppsListPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", mimeAppsListPath)
+	} else if err != nil && os.IsNotExist(err) {
+		mimeAppsListText = []byte{}
+	}
+	mimeAppsList := parseConfigFile(mimeAppsListPath, mimeAppsListText)
+	for _, mimeEntry := range mimeEntries {
+		desktopID := desktopEntryID(mimeEntry)
+		for _, mimeType := range mimeEntry.mimeTypes {
+			registerAssociation(m, mimeAppsListPath, mimeAppsList, mimeType, desktopID, mode)
+		}
+	}
+	if err := w.writeFileWithConfirmation(
+		mimeAppsListPath,
+		[]byte(mimeAppsList.String()),
+	); err != nil {
+		return errors.Wrap(err, "failed to mime association file")
+	}
+	if err := m.save(w); err != nil {
+		return err
+	}
+	if mode == associationModeDefault {
+		if err := reportShadowingEntries(mimeAppsListPath, mimeEntries); err != nil {
+			return err
+		}
+	}
+	return w.finish()
+}
+
+// Version of the Desktop Entry Specification the generated entries follow
+const desktopEntrySpecVersion = "1.5"
+
+// desktopEntryTranslation is a translation of Name and Comment of the
+// generated desktop entries. %s is replaced with the extension or the URL
+// scheme; linkComment is the Comment for URL schemes.
+type desktopEntryTranslation struct {
+	locale      string
+	name        string
+	comment     string
+	linkComment string
+}
+
+var desktopEntryTranslations = []desktopEntryTranslation{
+	{"de", "Mit Windows öffnen (%s)", "%s-Dateien mit der Standardanwendung von Windows öffnen", "%s-Links mit der Standardanwendung von Windows öffnen"},
+	{"ja", "Windows で開く (%s)", "%s ファイルを Windows の既定のアプリで開きます", "%s リンクを Windows の既定のアプリで開きます"},
+}
+
+// Distribution names that need no quoting in Exec
+var distroNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// desktopEntryFor generates the desktop entry that proxies the extension or
+// the URL scheme. The version of setup-wsl-open is recorded in
+// X-WSL-Open-Proxy-Version.
+func desktopEntryFor(mimeEntry mimeEntry) *xdgini.Config {
+	_, isScheme := entryScheme(mimeEntry)
+	comment := "Open %s files with the default Windows application"
+	exec := fmt.Sprintf("wsl-open-proxy.exe --ext %s %%f", mimeEntry.extension)
+	if isScheme {
+		comment = "Open %s links with the default Windows application"
+		exec = "wsl-open-proxy.exe --url %u"
+	}
+	// Windows processes do not see $WSL_DISTRO_NAME unless shared through
+	// WSLENV; the distribution is needed to translate the path
+	if distro := os.Getenv("WSL_DISTRO_NAME"); distroNamePattern.MatchString(distro) {
+		exec = strings.Replace(exec, "wsl-open-proxy.exe", "wsl-open-proxy.exe --distro "+distro, 1)
+	}
+
+	entries := map[string]*xdgini.ConfigEntry{}
+	order := 0
+	add := func(key string, value string) {
+		order++
+		entries[key] = xdgini.OrderedValue(value, order)
+	}
+	add("Type", "Application")
+	add("Version", desktopEntrySpecVersion)
+	add("Name", fmt.Sprintf("Open with Windows (%s)", mimeEntry.extension))
+	for _, tr := range desktopEntryTranslations {
+		add(xdgini.LocalizedKey("Name", tr.locale), fmt.Sprintf(tr.name, mimeEntry.extension))
+	}
+	add("Comment", fmt.Sprintf(comment, mimeEntry.extension))
+	for _, tr := range desktopEntryTranslations {
+		trComment := tr.comment
+		if isScheme {
+			trComment = tr.linkComment
+		}
+		add(xdgini.LocalizedKey("Comment", tr.locale), fmt.Sprintf(trComment, mimeEntry.extension))
+	}
+	add("NoDisplay", "true")
+	add("Exec", exec)
+	add("MimeType", xdgini.JoinList(mimeEntry.mimeTypes, true))
+	add("X-WSL-Open-Proxy-Version", wslopenproxy.Version)
+	return &xdgini.Config{
+		Groups: map[string]*xdgini.ConfigGroup{
+			"Desktop Entry": {
+				Raws:    xdgini.WithOrder(1),
+				Entries: entries,
+			},
+		},
+	}
+}
+
+// registerAssociation associates the MIME type with the desktop file ID in
+// the mimeapps.list and records it in the manifest.
+func registerAssociation(m *manifest, mimeAppsListPath string, mimeAppsList *xdgini.Config, mimeType string, desktopID string, mode string) {
+	switch mode {
+	case associationModeAdded:
+		addedAssociations := mimeAppsList.CreateGroup("Added Associations")
+		addedAssociations.AppendToList(mimeType, desktopID)
+		m.recordAssociation(mimeAppsListPath, "Added Associations", mimeType, desktopID, nil)
+	default:
+		defaultApplications := mimeAppsList.CreateGroup("Default Applications")
+		var previousValue *string
+		if entry, ok := defaultApplications.Entries[mimeType]; ok && entry.Value != desktopID {
+			value := entry.Value
+			previousValue = &value
+		}
+		defaultApplications.CreateEntry(mimeType, desktopID)
+		m.recordAssociation(mimeAppsListPath, "Default Applications", mimeType, desktopID, previousValue)
+	}
+	// A removed association would hide the desktop entry from the list
+	if removedAssociations, ok := mimeAppsList.Groups["Removed Associations"]; ok {
+		removedAssociations.RemoveFromList(mimeType, desktopID)
+		if len(removedAssociations.Entries) == 0 {
+			mimeAppsList.DeleteGroup("Removed Associations")
+		}
+	}
+}
+
+// updateMimeInfoCache regenerates mimeinfo.cache in the user's applications
+// directory, as update-desktop-database does, for the tools that do not scan
+// the desktop entries themselves. In dry-run mode, the cache is compared with
+// the desktop entries currently on disk.
+func updateMimeInfoCache(w *fileWriter) error {
+	appDir := path.Join(xdg.DataHome, "applications")
+	if _, err := os.Stat(appDir); err != nil && os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to check existence of %s", appDir)
+	}
+	cache, err := mimeapps.GenerateCache(appDir)
+	if err != nil {
+		return err
+	}
+	return w.writeGeneratedFile(path.Join(appDir, "mimeinfo.cache"), []byte(cache.String()))
+}
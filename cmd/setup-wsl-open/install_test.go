@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestInstallMultipleMediaGroups(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "")
+	if err := os.MkdirAll(path.Join(home, ".local/share/applications"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "[Default Applications]\n# My PDF viewer\napplication/pdf=evince.desktop\n")
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf", "html"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mimeEntry := range append(mediaGroups["pdf"], mediaGroups["html"]...) {
+		if _, err := os.Stat(desktopEntryPath(mimeEntry)); err != nil {
+			t.Errorf("desktop entry for %s is not created: %v", mimeEntry.extension, err)
+		}
+	}
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Default Applications]\n" +
+		"# My PDF viewer\n" +
+		"application/pdf=wsl-open-proxy-pdf.desktop\n" +
+		"text/html=wsl-open-proxy-html.desktop\n" +
+		"x-scheme-handler/about=wsl-open-proxy-html.desktop\n" +
+		"x-scheme-handler/http=wsl-open-proxy-html.desktop\n" +
+		"x-scheme-handler/https=wsl-open-proxy-html.desktop\n" +
+		"x-scheme-handler/unknown=wsl-open-proxy-html.desktop\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+
+	content, err = os.ReadFile(path.Join(home, ".local/share/applications/mimeinfo.cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[MIME Cache]\n" +
+		"application/pdf=wsl-open-proxy-pdf.desktop;\n" +
+		"text/html=wsl-open-proxy-html.desktop;\n" +
+		"x-scheme-handler/about=wsl-open-proxy-html.desktop;\n" +
+		"x-scheme-handler/http=wsl-open-proxy-html.desktop;\n" +
+		"x-scheme-handler/https=wsl-open-proxy-html.desktop;\n" +
+		"x-scheme-handler/unknown=wsl-open-proxy-html.desktop;\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeinfo.cache mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInstallAddedAssociations(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "")
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	original := "[Default Applications]\n" +
+		"application/pdf=evince.desktop\n" +
+		"\n" +
+		"[Added Associations]\n" +
+		"application/pdf=evince.desktop;okular.desktop;\n" +
+		"\n" +
+		"[Removed Associations]\n" +
+		"application/pdf=wsl-open-proxy-pdf.desktop;\n"
+	writeTestFile(t, mimeAppsListPath, original)
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeAdded, ""); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Default Applications]\n" +
+		"application/pdf=evince.desktop\n" +
+		"\n" +
+		"[Added Associations]\n" +
+		"application/pdf=evince.desktop;okular.desktop;wsl-open-proxy-pdf.desktop;\n" +
+		"\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := uninstall(context.Background(), w, mimeEntries, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[Default Applications]\n" +
+		"application/pdf=evince.desktop\n" +
+		"\n" +
+		"[Added Associations]\n" +
+		"application/pdf=evince.desktop;okular.desktop;\n" +
+		"\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch after uninstallation (-want +got):\n%s", diff)
+	}
+}
+
+func TestDesktopEntryIsValid(t *testing.T) {
+	for _, mimeEntry := range slices.Concat(mediaGroups["html"], mediaGroups["scheme"]) {
+		config := xdgini.ParseConfig(desktopEntryFor(mimeEntry).String())
+		if diagnostics := xdgini.ValidateDesktopEntry(config); len(diagnostics) > 0 {
+			t.Errorf("desktop entry for %s is invalid: %v", mimeEntry.extension, diagnostics)
+		}
+	}
+}
+
+func TestDesktopEntryIsLocalized(t *testing.T) {
+	group := desktopEntryFor(mediaGroups["pdf"][0]).Groups["Desktop Entry"]
+	testcases := []struct {
+		locale string
+		name   string
+	}{
+		{"ja_JP.UTF-8", "Windows で開く (.pdf)"},
+		{"de_AT", "Mit Windows öffnen (.pdf)"},
+		{"fr_FR", "Open with Windows (.pdf)"},
+	}
+	for _, tc := range testcases {
+		locale, _ := xdgini.ParseLocale(tc.locale)
+		name, err := group.LocalizedString("Name", locale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != tc.name {
+			t.Errorf("Name for %s = %q, want %q", tc.locale, name, tc.name)
+		}
+	}
+}
+
+func TestInstallKeepsBrokenLines(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "")
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "[Default Applications]\nbroken line\n[Added Associations\n")
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Default Applications]\n" +
+		"broken line\n" +
+		"application/pdf=wsl-open-proxy-pdf.desktop\n" +
+		"[Added Associations\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInstallKeepsCRLF(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "")
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "\ufeff[Default Applications]\r\ntext/plain=gedit.desktop\r\n")
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\ufeff[Default Applications]\r\n" +
+		"text/plain=gedit.desktop\r\n" +
+		"application/pdf=wsl-open-proxy-pdf.desktop\r\n"
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDesktopEntryForScheme(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "")
+	mimeEntry := mimeEntry{"mailto:", []string{"x-scheme-handler/mailto"}}
+	if got, want := desktopEntryID(mimeEntry), "wsl-open-proxy-scheme-mailto.desktop"; got != want {
+		t.Errorf("desktopEntryID() = %q, want %q", got, want)
+	}
+	group := desktopEntryFor(mimeEntry).Groups["Desktop Entry"]
+	if got, want := group.Entries["Exec"].Value, "wsl-open-proxy.exe --url %u"; got != want {
+		t.Errorf("Exec = %q, want %q", got, want)
+	}
+	if got, want := group.Entries["Comment"].Value, "Open mailto: links with the default Windows application"; got != want {
+		t.Errorf("Comment = %q, want %q", got, want)
+	}
+}
+
+func TestDesktopEntryForDistro(t *testing.T) {
+	testcases := []struct {
+		distro    string
+		extension string
+		exec      string
+	}{
+		{"Ubuntu-22.04", ".pdf", "wsl-open-proxy.exe --distro Ubuntu-22.04 --ext .pdf %f"},
+		{"Ubuntu-22.04", "mailto:", "wsl-open-proxy.exe --distro Ubuntu-22.04 --url %u"},
+		// Not safe to embed without quoting
+		{"My Distro", ".pdf", "wsl-open-proxy.exe --ext .pdf %f"},
+		{"", ".pdf", "wsl-open-proxy.exe --ext .pdf %f"},
+	}
+	for _, tc := range testcases {
+		t.Setenv("WSL_DISTRO_NAME", tc.distro)
+		group := desktopEntryFor(mimeEntry{tc.extension, nil}).Groups["Desktop Entry"]
+		if got := group.Entries["Exec"].Value; got != tc.exec {
+			t.Errorf("Exec for %q in %q = %q, want %q", tc.extension, tc.distro, got, tc.exec)
+		}
+	}
+}
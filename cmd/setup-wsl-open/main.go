@@ -1,29 +1,24 @@
 package main
 
 import (
-	"bytes"
-	"context"
 	"embed"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
-	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/adrg/xdg"
-	"github.com/c-bata/go-prompt"
 	"github.com/pkg/errors"
 	wslopenproxy "github.com/qnighy/wsl-open-proxy"
-	"github.com/qnighy/wsl-open-proxy/xdgini"
-	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 )
 
 //go:embed assets/*.keep assets/*
 var assets embed.FS
 
+// mimeEntry is an extension like ".pdf", or a URL scheme like "mailto:", with
+// the MIME types registered for it.
 type mimeEntry = struct {
 	extension string
 	mimeTypes []string
@@ -53,11 +48,27 @@ var mediaGroups = map[string][]mimeEntry{
 		{".webm", []string{"video/webm"}},
 		{".ogv", []string{"video/ogg"}},
 	},
+	"scheme": {
+		{"mailto:", []string{"x-scheme-handler/mailto"}},
+		{"tel:", []string{"x-scheme-handler/tel"}},
+		{"ms-settings:", []string{"x-scheme-handler/ms-settings"}},
+		{"vscode:", []string{"x-scheme-handler/vscode"}},
+		{"zoommtg:", []string{"x-scheme-handler/zoommtg"}},
+	},
 }
 
+// Media groups installed only if requested by name, as they take over
+// handlers usually provided by Linux applications
+var optInMediaGroups = []string{"scheme"}
+
 func main() {
 	updateBin := false
-	mediaGroupName := "html"
+	mediaGroupNames := []string{"html"}
+	allMediaGroups := false
+	var extensions []string
+	useMimeDB := false
+	mode := associationModeDefault
+	desktop := ""
 	var rootCmd = &cobra.Command{
 		Use:     "setup-wsl-open",
 		Version: wslopenproxy.Version,
@@ -65,20 +76,47 @@ func main() {
 			if len(args) > 0 {
 				return errors.New("too many arguments")
 			}
-			_, ok := mediaGroups[mediaGroupName]
-			if !ok {
-				return errors.Errorf("Unknown media group: %s", mediaGroupName)
+			if err := loadUserMediaGroups(); err != nil {
+				return err
+			}
+			if allMediaGroups {
+				mediaGroupNames = allMediaGroupNames()
+			} else if len(extensions) > 0 && !cmd.Flags().Changed("type") {
+				// Only the extensions are requested
+				mediaGroupNames = nil
+			}
+			if !slices.Contains(associationModes, mode) {
+				return errors.Errorf("Unknown mode: %s", mode)
+			}
+			if _, err := targetMimeAppsListPath(desktop); err != nil {
+				return err
+			}
+			mimeEntries, err := resolveMimeEntries(mediaGroupNames, extensions, useMimeDB)
+			if err != nil {
+				return err
+			}
+			w, err := fileWriterFromFlags(cmd)
+			if err != nil {
+				return err
 			}
 			cmd.SilenceUsage = true
-			return run(cmd.Context(), updateBin, mediaGroupName)
+			return install(cmd.Context(), w, updateBin, mimeEntries, mode, desktop)
 		},
 	}
-	mediaGroupNames := make([]string, 0, len(mediaGroups))
-	for name := range mediaGroups {
-		mediaGroupNames = append(mediaGroupNames, name)
-	}
 	rootCmd.Flags().BoolVarP(&updateBin, "update", "u", updateBin, "Update wsl-open-proxy.exe even if it is already installed")
-	rootCmd.Flags().StringVarP(&mediaGroupName, "type", "t", mediaGroupName, fmt.Sprintf("Media group to install (One of: %v)", mediaGroupNames))
+	rootCmd.Flags().StringSliceVarP(&mediaGroupNames, "type", "t", mediaGroupNames, fmt.Sprintf("Media groups to install, repeated or comma-separated (Any of: %v, or those defined in groups.ini)", sortedMediaGroupNames()))
+	rootCmd.Flags().BoolVar(&allMediaGroups, "all", allMediaGroups, fmt.Sprintf("Install all media groups except %v", optInMediaGroups))
+	rootCmd.MarkFlagsMutuallyExclusive("type", "all")
+	rootCmd.Flags().StringSliceVar(&extensions, "ext", extensions, "Extensions to install in addition to the media groups (like .docx), with MIME types looked up in shared-mime-info")
+	rootCmd.Flags().BoolVar(&useMimeDB, "mime-db", useMimeDB, "Also register MIME types and aliases found in shared-mime-info for each extension")
+	rootCmd.Flags().StringVar(&mode, "mode", mode, fmt.Sprintf("How to register the desktop entries in mimeapps.list (One of: %v); \"added\" lists them as alternatives without changing the default applications", associationModes))
+	rootCmd.Flags().StringVar(&desktop, "desktop", desktop, "Register in the mimeapps.list specific to the desktop environment (like GNOME or KDE), which takes precedence over mimeapps.list")
+	addFileWriterFlags(rootCmd)
+
+	rootCmd.AddCommand(newUninstallCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newRestoreCmd())
+	rootCmd.AddCommand(newValidateCmd())
 
 	err := rootCmd.Execute()
 	if err != nil {
@@ -87,170 +125,108 @@ func main() {
 	}
 }
 
-func run(ctx context.Context, updateBin bool, mediaGroupName string) error {
-	_ = ctx
-
-	exeInstallPath := path.Join(xdg.BinHome, "wsl-open-proxy.exe")
-	installBin := updateBin
-	_, err := os.Stat(exeInstallPath)
-	if err != nil && !os.IsNotExist(err) {
-		return errors.Wrap(err, "failed to check existence of wsl-open-proxy.exe")
-	} else if err != nil && os.IsNotExist(err) {
-		installBin = true
-	}
-
-	if installBin {
-		exeFile, err := assets.ReadFile(fmt.Sprintf("assets/wsl-open-proxy-%s.exe", runtime.GOARCH))
-		if err != nil && !os.IsNotExist(err) {
-			return errors.Wrap(err, "failed to read wsl-open-proxy.exe in assets")
-		} else if err != nil && os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Building wsl-open-proxy.exe from source...\n")
-			// Not found; alternative installation
-			cmd := exec.Command("go", "install", fmt.Sprintf("github.com/qnighy/wsl-open-proxy/cmd/wsl-open-proxy@v%s", wslopenproxy.Version))
-			cmd.Env = append(os.Environ(), "GOOS=windows", fmt.Sprintf("GOARCH=%s", runtime.GOARCH))
-			_, err := cmd.Output()
-			if err != nil {
-				return errors.Wrap(err, "failed to build wsl-open-proxy.exe from source")
-			}
-
-			fmt.Fprintf(os.Stderr, "Installing wsl-open-proxy.exe built from source...\n")
-			gobin := os.Getenv("GOBIN")
-			if gobin == "" {
-				gopath := os.Getenv("GOPATH")
-				if gopath != "" {
-					gobin = path.Join(os.Getenv("GOPATH"), "bin")
-				} else {
-					gobin = path.Join(os.Getenv("HOME"), "go", "bin")
-				}
-			}
-			gobinSuffixed := path.Join(gobin, fmt.Sprintf("windows_%s", runtime.GOARCH))
-			exeBuiltPath := path.Join(gobinSuffixed, "wsl-open-proxy.exe")
-			if err := os.Rename(exeBuiltPath, exeInstallPath); err != nil {
-				return errors.Wrap(err, "failed to move wsl-open-proxy.exe")
-			}
-		} else {
-			fmt.Fprintf(os.Stderr, "Installing prebuilt wsl-open-proxy.exe...\n")
-			if err := os.WriteFile(exeInstallPath, exeFile, 0755); err != nil {
-				return errors.Wrap(err, "failed to write wsl-open-proxy.exe")
-			}
-		}
-	} else {
-		fmt.Fprintf(os.Stderr, "wsl-open-proxy.exe is already installed\n")
-	}
+func addFileWriterFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Apply all changes without asking for confirmation")
+	cmd.Flags().Bool("dry-run", false, "Show changes without applying them; fails if any change is needed")
+	cmd.Flags().String("diff-format", "", fmt.Sprintf("Format of the diffs shown before applying changes (One of: %v; default: color for terminals and unified otherwise)", diffFormats))
+}
 
-	mediaGroup, ok := mediaGroups[mediaGroupName]
-	if !ok {
-		return errors.Errorf("Unknown media group: %s", mediaGroupName)
+func fileWriterFromFlags(cmd *cobra.Command) (*fileWriter, error) {
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Fprintf(os.Stderr, "Registering desktop entries for %s files...\n", mediaGroupName)
-	for _, mimeEntry := range mediaGroup {
-		desktopEntry := &xdgini.Config{
-			Groups: map[string]*xdgini.ConfigGroup{
-				"Desktop Entry": {
-					Raws: xdgini.WithOrder(1),
-					Entries: map[string]*xdgini.ConfigEntry{
-						"Type":      xdgini.OrderedValue("Application", 1),
-						"Version":   xdgini.OrderedValue(wslopenproxy.Version, 2),
-						"Name":      xdgini.OrderedValue(fmt.Sprintf("WSL Open Proxy (%s)", mimeEntry.extension), 3),
-						"NoDisplay": xdgini.OrderedValue("true", 4),
-						"Exec":      xdgini.OrderedValue(fmt.Sprintf("wsl-open-proxy.exe --ext %s %%f", mimeEntry.extension), 5),
-						"MimeType":  xdgini.OrderedValue(strings.Join(mimeEntry.mimeTypes, ";"), 6),
-					},
-				},
-			},
-		}
-		extensionName := strings.TrimPrefix(mimeEntry.extension, ".")
-		if err := writeFileWithConfirmation(
-			path.Join(xdg.DataHome, "applications", fmt.Sprintf("wsl-open-proxy-%s.desktop", extensionName)),
-			[]byte(desktopEntry.String()),
-			colored(os.Stderr),
-		); err != nil {
-			return errors.Wrap(err, "failed to write application config")
-		}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Fprintf(os.Stderr, "Registering mime associations...\n")
-	mimeAppsListPath := path.Join(xdg.ConfigHome, "mimeapps.list")
-	mimeAppsListText, err := os.ReadFile(mimeAppsListPath)
-	if err != nil && !os.IsNotExist(err) {
-		return errors.Wrap(err, "failed to read mimeapps.list")
-	} else if err != nil && os.IsNotExist(err) {
-		mimeAppsListText = []byte{}
+	if yes && dryRun {
+		return nil, errors.New("--yes and --dry-run cannot be used together")
 	}
-	mimeAppsList := xdgini.ParseConfig(string(mimeAppsListText))
-	defaultApplications := mimeAppsList.CreateGroup("Default Applications")
-	for _, mimeEntry := range mediaGroup {
-		extensionName := strings.TrimPrefix(mimeEntry.extension, ".")
-		for _, mimeType := range mimeEntry.mimeTypes {
-			defaultApplications.CreateEntry(mimeType, fmt.Sprintf("wsl-open-proxy-%s.desktop", extensionName))
-		}
+	diffFormat, err := cmd.Flags().GetString("diff-format")
+	if err != nil {
+		return nil, err
 	}
-	if err := writeFileWithConfirmation(
-		mimeAppsListPath,
-		[]byte(mimeAppsList.String()),
-		colored(os.Stderr),
-	); err != nil {
-		return errors.Wrap(err, "failed to mime association file")
+	if diffFormat != "" && !slices.Contains(diffFormats, diffFormat) {
+		return nil, errors.Errorf("Unknown diff format: %s", diffFormat)
 	}
-	fmt.Fprintf(os.Stderr, "Done\n")
-	return nil
+	return newFileWriter(yes, dryRun, diffFormat), nil
 }
 
-func writeFileWithConfirmation(filePath string, data []byte, coloredStderr bool) error {
-	oldContent, err := os.ReadFile(filePath)
-	if err != nil && !os.IsNotExist(err) {
-		return errors.Wrapf(err, "failed to read %s", filePath)
-	} else if err == nil {
-		if string(oldContent) == string(data) {
-			// No need to update
-			return nil
-		}
-		dmp := diffmatchpatch.New()
-		diffs := dmp.DiffMain(string(oldContent), string(data), false)
-		fmt.Fprintf(os.Stderr, "Need to apply the following changes to %s:\n", filePath)
-		if coloredStderr {
-			fmt.Fprintf(os.Stderr, "%s\n", dmp.DiffPrettyText(diffs))
-		} else {
-			fmt.Fprintf(os.Stderr, "%s\n", diffText(diffs))
+// validateMediaGroupNames checks the media group names and removes duplicates.
+func validateMediaGroupNames(names []string) ([]string, error) {
+	var validated []string
+	for _, name := range names {
+		if _, ok := mediaGroups[name]; !ok {
+			return nil, errors.Errorf("Unknown media group: %s", name)
 		}
-		answer := prompt.Input("Apply this change? [y/N]", yesNoCompleter)
-		if answer != "y" && answer != "Y" {
-			return errors.Errorf("Overwrite to %s is canceled", filePath)
+		if !slices.Contains(validated, name) {
+			validated = append(validated, name)
 		}
 	}
+	return validated, nil
+}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return errors.Wrapf(err, "failed to write %s", filePath)
+func sortedMediaGroupNames() []string {
+	mediaGroupNames := make([]string, 0, len(mediaGroups))
+	for name := range mediaGroups {
+		mediaGroupNames = append(mediaGroupNames, name)
 	}
-	return nil
+	slices.Sort(mediaGroupNames)
+	return mediaGroupNames
 }
 
-func diffText(diffs []diffmatchpatch.Diff) string {
-	var buff bytes.Buffer
-	for _, diff := range diffs {
-		text := diff.Text
+// allMediaGroupNames returns the media groups installed by --all.
+func allMediaGroupNames() []string {
+	return slices.DeleteFunc(sortedMediaGroupNames(), func(name string) bool {
+		return slices.Contains(optInMediaGroups, name)
+	})
+}
 
-		switch diff.Type {
-		case diffmatchpatch.DiffInsert:
-			_, _ = buff.WriteString(text)
-		case diffmatchpatch.DiffDelete:
-			_, _ = buff.WriteString(text)
-		case diffmatchpatch.DiffEqual:
-			_, _ = buff.WriteString(text)
-		}
+func exeInstallPath() string {
+	return path.Join(xdg.BinHome, "wsl-open-proxy.exe")
+}
+
+func mimeAppsListPath() string {
+	return path.Join(xdg.ConfigHome, "mimeapps.list")
+}
+
+// entryScheme returns the URL scheme of the entry, or false if it is for an
+// extension.
+func entryScheme(entry mimeEntry) (string, bool) {
+	if !strings.HasSuffix(entry.extension, ":") {
+		return "", false
 	}
+	return strings.TrimSuffix(entry.extension, ":"), true
+}
 
-	return buff.String()
+// desktopEntryID returns the desktop file ID registered for the extension or
+// the URL scheme.
+func desktopEntryID(entry mimeEntry) string {
+	if scheme, ok := entryScheme(entry); ok {
+		return fmt.Sprintf("wsl-open-proxy-scheme-%s.desktop", strings.ToLower(scheme))
+	}
+	extensionName := strings.TrimPrefix(entry.extension, ".")
+	return fmt.Sprintf("wsl-open-proxy-%s.desktop", extensionName)
 }
 
-func yesNoCompleter(d prompt.Document) []prompt.Suggest {
-	return []prompt.Suggest{
-		{Text: "y", Description: "Apply this change"},
-		{Text: "n", Description: "Do not apply this change"},
+// mimeEntryForDesktopID is the reverse of desktopEntryID. The MIME types are
+// not known from the ID and left empty.
+func mimeEntryForDesktopID(desktopID string) (mimeEntry, bool) {
+	name, ok := strings.CutPrefix(desktopID, "wsl-open-proxy-")
+	if !ok {
+		return mimeEntry{}, false
+	}
+	name, ok = strings.CutSuffix(name, ".desktop")
+	if !ok || name == "" {
+		return mimeEntry{}, false
+	}
+	if scheme, ok := strings.CutPrefix(name, "scheme-"); ok {
+		return mimeEntry{scheme + ":", nil}, true
 	}
+	return mimeEntry{"." + name, nil}, true
 }
 
-func colored(f *os.File) bool {
-	return term.IsTerminal(int(f.Fd())) && os.Getenv("NO_COLOR") == ""
+func desktopEntryPath(entry mimeEntry) string {
+	return path.Join(xdg.DataHome, "applications", desktopEntryID(entry))
 }
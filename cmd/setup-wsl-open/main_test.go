@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+// setupXDG points the XDG base directories to a temporary directory.
+func setupXDG(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Cleanup(xdg.Reload)
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", path.Join(home, ".config"))
+	t.Setenv("XDG_CONFIG_DIRS", path.Join(home, "etc/xdg"))
+	t.Setenv("XDG_DATA_HOME", path.Join(home, ".local/share"))
+	t.Setenv("XDG_DATA_DIRS", path.Join(home, "usr/share"))
+	t.Setenv("XDG_STATE_HOME", path.Join(home, ".local/state"))
+	t.Setenv("XDG_BIN_HOME", path.Join(home, ".local/bin"))
+	t.Setenv("XDG_CURRENT_DESKTOP", "")
+	t.Setenv("WSL_DISTRO_NAME", "")
+	xdg.Reload()
+	return home
+}
+
+func writeTestFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllMediaGroupNames(t *testing.T) {
+	names := allMediaGroupNames()
+	if slices.Contains(names, "scheme") {
+		t.Errorf("allMediaGroupNames() = %v, want without scheme", names)
+	}
+	if !slices.Contains(names, "html") || !slices.Contains(sortedMediaGroupNames(), "scheme") {
+		t.Errorf("allMediaGroupNames() = %v, sortedMediaGroupNames() = %v", names, sortedMediaGroupNames())
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	wslopenproxy "github.com/qnighy/wsl-open-proxy"
+)
+
+// manifest records the files and associations that setup-wsl-open has
+// installed, so that later runs can tell them from the user's own changes.
+type manifest struct {
+	// Version of setup-wsl-open that last updated the manifest
+	Version        string                 `json:"version"`
+	Exe            *manifestExe           `json:"exe,omitempty"`
+	DesktopEntries []manifestDesktopEntry `json:"desktopEntries"`
+	Associations   []manifestAssociation  `json:"associations"`
+}
+
+type manifestExe struct {
+	Path string `json:"path"`
+	// Version of the installed wsl-open-proxy.exe; empty if unknown
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+type manifestDesktopEntry struct {
+	Path      string   `json:"path"`
+	Extension string   `json:"extension"`
+	MimeTypes []string `json:"mimeTypes"`
+	SHA256    string   `json:"sha256"`
+}
+
+type manifestAssociation struct {
+	// Path to the mimeapps.list
+	File string `json:"file"`
+	// Group in the mimeapps.list, like "Default Applications"
+	Group    string `json:"group"`
+	MimeType string `json:"mimeType"`
+	// The desktop file ID registered by setup-wsl-open
+	DesktopID string `json:"desktopId"`
+	// The value replaced by setup-wsl-open; nil if there was no entry
+	PreviousValue *string `json:"previousValue,omitempty"`
+}
+
+func manifestPath() string {
+	return path.Join(xdg.StateHome, "wsl-open-proxy", "manifest.json")
+}
+
+// loadManifest reads the manifest; an empty one is returned if it does not
+// exist yet.
+func loadManifest() (*manifest, error) {
+	manifestPath := manifestPath()
+	data, err := os.ReadFile(manifestPath)
+	if err != nil && os.IsNotExist(err) {
+		return &manifest{}, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", manifestPath)
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", manifestPath)
+	}
+	return m, nil
+}
+
+// save writes the manifest, or removes it if nothing is recorded anymore.
+// Nothing is written in dry-run mode.
+func (m *manifest) save(w *fileWriter) error {
+	if w.dryRun {
+		return nil
+	}
+	manifestPath := manifestPath()
+	if m.Exe == nil && len(m.DesktopEntries) == 0 && len(m.Associations) == 0 {
+		if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove %s", manifestPath)
+		}
+		return nil
+	}
+	m.Version = wslopenproxy.Version
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := atomicWriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", manifestPath)
+	}
+	return nil
+}
+
+func (m *manifest) desktopEntry(filePath string) *manifestDesktopEntry {
+	idx := slices.IndexFunc(m.DesktopEntries, func(e manifestDesktopEntry) bool {
+		return e.Path == filePath
+	})
+	if idx < 0 {
+		return nil
+	}
+	return &m.DesktopEntries[idx]
+}
+
+func (m *manifest) recordDesktopEntry(entry manifestDesktopEntry) {
+	if existing := m.desktopEntry(entry.Path); existing != nil {
+		*existing = entry
+		return
+	}
+	m.DesktopEntries = append(m.DesktopEntries, entry)
+}
+
+func (m *manifest) forgetDesktopEntry(filePath string) {
+	m.DesktopEntries = slices.DeleteFunc(m.DesktopEntries, func(e manifestDesktopEntry) bool {
+		return e.Path == filePath
+	})
+}
+
+// mimeEntries lists the extensions and URL schemes recorded in the manifest,
+// including those only known from their associations.
+func (m *manifest) mimeEntries() []mimeEntry {
+	var mimeEntries []mimeEntry
+	for _, e := range m.DesktopEntries {
+		mimeEntries = addMimeTypes(mimeEntries, e.Extension, e.MimeTypes)
+	}
+	for _, a := range m.Associations {
+		if e, ok := mimeEntryForDesktopID(a.DesktopID); ok {
+			mimeEntries = addMimeTypes(mimeEntries, e.extension, nil)
+		}
+	}
+	return mimeEntries
+}
+
+func (m *manifest) association(file string, group string, mimeType string) *manifestAssociation {
+	idx := slices.IndexFunc(m.Associations, func(a manifestAssociation) bool {
+		return a.File == file && a.Group == group && a.MimeType == mimeType
+	})
+	if idx < 0 {
+		return nil
+	}
+	return &m.Associations[idx]
+}
+
+// recordAssociation records that the MIME type is associated with the
+// desktop file ID. The previous value is only recorded for the first time
+// so that the value before setup-wsl-open is kept over reinstallations.
+func (m *manifest) recordAssociation(file string, group string, mimeType string, desktopID string, previousValue *string) {
+	if existing := m.association(file, group, mimeType); existing != nil {
+		existing.DesktopID = desktopID
+		return
+	}
+	m.Associations = append(m.Associations, manifestAssociation{
+		File:          file,
+		Group:         group,
+		MimeType:      mimeType,
+		DesktopID:     desktopID,
+		PreviousValue: previousValue,
+	})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileSHA256 returns the hash of the file content, or an empty string if the
+// file does not exist.
+func fileSHA256(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil && os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	return sha256Hex(data), nil
+}
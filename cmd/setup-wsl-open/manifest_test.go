@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInstallRecordsManifest(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "exe")
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "[Default Applications]\napplication/pdf=evince.desktop\n")
+
+	w := &fileWriter{yes: true}
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := loadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousValue := "evince.desktop"
+	want := &manifest{
+		Version: m.Version,
+		Exe: &manifestExe{
+			Path:   exeInstallPath(),
+			SHA256: sha256Hex([]byte("exe")),
+		},
+		DesktopEntries: []manifestDesktopEntry{
+			{
+				Path:      desktopEntryPath(mimeEntries[0]),
+				Extension: ".pdf",
+				MimeTypes: []string{"application/pdf"},
+				SHA256:    sha256Hex([]byte(desktopEntryFor(mimeEntries[0]).String())),
+			},
+		},
+		Associations: []manifestAssociation{
+			{
+				File:          mimeAppsListPath,
+				Group:         "Default Applications",
+				MimeType:      "application/pdf",
+				DesktopID:     "wsl-open-proxy-pdf.desktop",
+				PreviousValue: &previousValue,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("manifest mismatch (-want +got):\n%s", diff)
+	}
+
+	// Reinstallation keeps the value before the first installation
+	if err := install(context.Background(), w, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+	m, err = loadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("manifest mismatch after reinstallation (-want +got):\n%s", diff)
+	}
+
+	if err := uninstall(context.Background(), w, mimeEntries, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(manifestPath()); !os.IsNotExist(err) {
+		t.Errorf("manifest is not removed after uninstallation: %v", err)
+	}
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[Default Applications]\napplication/pdf=evince.desktop\n", string(content)); diff != "" {
+		t.Errorf("previous default application is not restored (-want +got):\n%s", diff)
+	}
+}
+
+func TestUninstallConfirmsModifiedDesktopEntry(t *testing.T) {
+	setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "exe")
+
+	mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), &fileWriter{yes: true}, false, mimeEntries, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+	desktopEntryPath := desktopEntryPath(mimeEntries[0])
+	writeTestFile(t, desktopEntryPath, "[Desktop Entry]\n# Edited by hand\n")
+	if err := os.Remove(mimeAppsListPath()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --yes, the edited file is not removed silently
+	err = uninstall(context.Background(), &fileWriter{}, mimeEntries, false, "")
+	if err == nil || !strings.Contains(err.Error(), desktopEntryPath) {
+		t.Fatalf("expected an error for the modified desktop entry, got %v", err)
+	}
+	if _, err := os.Stat(desktopEntryPath); err != nil {
+		t.Errorf("modified desktop entry is removed: %v", err)
+	}
+}
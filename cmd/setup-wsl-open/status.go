@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	wslopenproxy "github.com/qnighy/wsl-open-proxy"
+	"github.com/qnighy/wsl-open-proxy/mimeapps"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+	"github.com/spf13/cobra"
+)
+
+type statusReport struct {
+	Exe         exeStatus          `json:"exe"`
+	MediaGroups []mediaGroupStatus `json:"mediaGroups"`
+	// Desktop entries in the manifest that belong to no media group, like
+	// those installed with --ext
+	Extensions []desktopEntryStatus `json:"extensions"`
+}
+
+type exeStatus struct {
+	Path      string `json:"path"`
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"`
+	UpToDate  bool   `json:"upToDate"`
+	Error     string `json:"error,omitempty"`
+}
+
+type mediaGroupStatus struct {
+	Name string `json:"name"`
+	// Whether the group is installed only if requested by name
+	OptIn bool `json:"optIn"`
+	// Whether any desktop entry of the group exists
+	Installed      bool                 `json:"installed"`
+	DesktopEntries []desktopEntryStatus `json:"desktopEntries"`
+}
+
+type desktopEntryStatus struct {
+	Extension string `json:"extension"`
+	Path      string `json:"path"`
+	Exists    bool   `json:"exists"`
+	UpToDate  bool   `json:"upToDate"`
+	// Whether the file has been edited since setup-wsl-open wrote it
+	Modified  bool             `json:"modified"`
+	MimeTypes []mimeTypeStatus `json:"mimeTypes"`
+}
+
+type mimeTypeStatus struct {
+	MimeType string `json:"mimeType"`
+	// Whether our mimeapps.list maps the MIME type to the desktop entry
+	Registered bool `json:"registered"`
+	// Whether our mimeapps.list lists the desktop entry in "Added Associations"
+	Added bool `json:"added"`
+	// The mimeapps.list or mimeinfo.cache that determines the application
+	// used for the MIME type, if any
+	EffectivePath string `json:"effectivePath,omitempty"`
+	// The desktop file ID of the application used for the MIME type
+	EffectiveDesktopID string `json:"effectiveDesktopId,omitempty"`
+	// Whether the desktop entry is the application used for the MIME type
+	Effective bool `json:"effective"`
+}
+
+func newStatusCmd() *cobra.Command {
+	jsonOutput := false
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report the current configuration of wsl-open-proxy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("too many arguments")
+			}
+			cmd.SilenceUsage = true
+			report, err := status(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			printStatus(report)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", jsonOutput, "Output in JSON format")
+	return cmd
+}
+
+func status(ctx context.Context) (*statusReport, error) {
+	if err := loadUserMediaGroups(); err != nil {
+		return nil, err
+	}
+	report := &statusReport{
+		Exe: checkExe(ctx, exeInstallPath()),
+	}
+	m, err := loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	// The files where setup-wsl-open may have registered associations
+	ownMimeAppsLists := map[string]bool{mimeAppsListPath(): true}
+	for _, a := range m.Associations {
+		ownMimeAppsLists[a.File] = true
+	}
+
+	ownMimeAppsListPaths := slices.Sorted(maps.Keys(ownMimeAppsLists))
+	var mimeAppsLists []*xdgini.Config
+	for _, mimeAppsListPath := range ownMimeAppsListPaths {
+		mimeAppsListText, err := os.ReadFile(mimeAppsListPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "failed to read %s", mimeAppsListPath)
+		}
+		mimeAppsLists = append(mimeAppsLists, xdgini.ParseConfig(string(mimeAppsListText)))
+	}
+
+	// shared-mime-info is only needed for the subclass fallback
+	mimeDB, err := sharedmime.Load(sharedmime.DefaultDirs())
+	if err != nil {
+		mimeDB = nil
+	}
+	appsDB, err := mimeapps.Load(mimeapps.DefaultDirs(), mimeDB)
+	if err != nil {
+		return nil, err
+	}
+
+	checkEntry := func(mimeEntry mimeEntry) (desktopEntryStatus, error) {
+		desktopID := desktopEntryID(mimeEntry)
+		entryStatus := desktopEntryStatus{
+			Extension: mimeEntry.extension,
+			Path:      desktopEntryPath(mimeEntry),
+		}
+		content, err := os.ReadFile(entryStatus.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return entryStatus, errors.Wrapf(err, "failed to read %s", entryStatus.Path)
+		} else if err == nil {
+			entryStatus.Exists = true
+			entryStatus.UpToDate = string(content) == desktopEntryFor(mimeEntry).String()
+			if recorded := m.desktopEntry(entryStatus.Path); recorded != nil {
+				entryStatus.Modified = sha256Hex(content) != recorded.SHA256
+			}
+		}
+		for _, mimeType := range mimeEntry.mimeTypes {
+			mimeStatus := mimeTypeStatus{
+				MimeType: mimeType,
+			}
+			for _, mimeAppsList := range mimeAppsLists {
+				if addedAssociations, ok := mimeAppsList.Groups["Added Associations"]; ok {
+					if entry, ok := addedAssociations.Entries[mimeType]; ok && slices.Contains(xdgini.SplitList(entry.Value), desktopID) {
+						mimeStatus.Added = true
+					}
+				}
+				if value, ok := defaultApplicationsValue(mimeAppsList, mimeType); ok && slices.Contains(xdgini.SplitList(value), desktopID) {
+					mimeStatus.Registered = true
+				}
+			}
+			if association, ok := appsDB.DefaultApplication(mimeType); ok {
+				mimeStatus.EffectivePath = association.Source
+				mimeStatus.EffectiveDesktopID = association.DesktopID
+				mimeStatus.Effective = association.DesktopID == desktopID
+			}
+			entryStatus.MimeTypes = append(entryStatus.MimeTypes, mimeStatus)
+		}
+		return entryStatus, nil
+	}
+
+	reported := map[string]bool{}
+	for _, mediaGroupName := range sortedMediaGroupNames() {
+		groupStatus := mediaGroupStatus{
+			Name:  mediaGroupName,
+			OptIn: slices.Contains(optInMediaGroups, mediaGroupName),
+		}
+		for _, mimeEntry := range mediaGroups[mediaGroupName] {
+			entryStatus, err := checkEntry(mimeEntry)
+			if err != nil {
+				return nil, err
+			}
+			groupStatus.Installed = groupStatus.Installed || entryStatus.Exists
+			groupStatus.DesktopEntries = append(groupStatus.DesktopEntries, entryStatus)
+			reported[entryStatus.Path] = true
+		}
+		report.MediaGroups = append(report.MediaGroups, groupStatus)
+	}
+	for _, recorded := range m.DesktopEntries {
+		if reported[recorded.Path] {
+			continue
+		}
+		entryStatus, err := checkEntry(mimeEntry{recorded.Extension, recorded.MimeTypes})
+		if err != nil {
+			return nil, err
+		}
+		report.Extensions = append(report.Extensions, entryStatus)
+		reported[recorded.Path] = true
+	}
+	return report, nil
+}
+
+func checkExe(ctx context.Context, exePath string) exeStatus {
+	exe := exeStatus{
+		Path: exePath,
+	}
+	if _, err := os.Stat(exePath); err != nil {
+		if !os.IsNotExist(err) {
+			exe.Error = err.Error()
+		}
+		return exe
+	}
+	exe.Installed = true
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, exePath, "--version").Output()
+	if err != nil {
+		exe.Error = errors.Wrap(err, "failed to get version").Error()
+		return exe
+	}
+	// The output looks like "wsl-open-proxy version 0.1.2"
+	fields := strings.Fields(string(out))
+	if len(fields) > 0 {
+		exe.Version = fields[len(fields)-1]
+	}
+	exe.UpToDate = exe.Version == wslopenproxy.Version
+	return exe
+}
+
+// mimeAppsListPaths lists the mimeapps.list files in the order of precedence.
+func mimeAppsListPaths() []string {
+	return mimeapps.DefaultDirs().MimeAppsListPaths()
+}
+
+func defaultApplicationsValue(mimeAppsList *xdgini.Config, mimeType string) (string, bool) {
+	defaultApplications, ok := mimeAppsList.Groups["Default Applications"]
+	if !ok {
+		return "", false
+	}
+	entry, ok := defaultApplications.Entries[mimeType]
+	if !ok {
+		return "", false
+	}
+	return entry.Value, true
+}
+
+func printStatus(report *statusReport) {
+	switch {
+	case !report.Exe.Installed:
+		fmt.Printf("wsl-open-proxy.exe: not installed (%s)\n", report.Exe.Path)
+	case report.Exe.Error != "":
+		fmt.Printf("wsl-open-proxy.exe: installed at %s (%s)\n", report.Exe.Path, report.Exe.Error)
+	case !report.Exe.UpToDate:
+		fmt.Printf("wsl-open-proxy.exe: version %s installed at %s (expected %s; run with --update)\n", report.Exe.Version, report.Exe.Path, wslopenproxy.Version)
+	default:
+		fmt.Printf("wsl-open-proxy.exe: version %s installed at %s\n", report.Exe.Version, report.Exe.Path)
+	}
+
+	for _, groupStatus := range report.MediaGroups {
+		if groupStatus.OptIn && !groupStatus.Installed {
+			fmt.Printf("%s: not installed (install with -t %s)\n", groupStatus.Name, groupStatus.Name)
+			continue
+		}
+		fmt.Printf("%s:\n", groupStatus.Name)
+		for _, entryStatus := range groupStatus.DesktopEntries {
+			printDesktopEntryStatus(entryStatus)
+		}
+	}
+	if len(report.Extensions) > 0 {
+		fmt.Printf("other extensions:\n")
+		for _, entryStatus := range report.Extensions {
+			printDesktopEntryStatus(entryStatus)
+		}
+	}
+}
+
+func printDesktopEntryStatus(entryStatus desktopEntryStatus) {
+	switch {
+	case !entryStatus.Exists:
+		fmt.Printf("  %s: desktop entry not found (%s)\n", entryStatus.Extension, entryStatus.Path)
+	case entryStatus.Modified:
+		fmt.Printf("  %s: desktop entry has been modified since installation (%s)\n", entryStatus.Extension, entryStatus.Path)
+	case !entryStatus.UpToDate:
+		fmt.Printf("  %s: desktop entry is outdated or modified (%s)\n", entryStatus.Extension, entryStatus.Path)
+	default:
+		fmt.Printf("  %s: desktop entry is up to date (%s)\n", entryStatus.Extension, entryStatus.Path)
+	}
+	for _, mimeStatus := range entryStatus.MimeTypes {
+		switch {
+		case mimeStatus.Effective:
+			fmt.Printf("    %s: registered\n", mimeStatus.MimeType)
+		case mimeStatus.Registered:
+			fmt.Printf("    %s: registered, but overridden by %s in %s\n", mimeStatus.MimeType, mimeStatus.EffectiveDesktopID, mimeStatus.EffectivePath)
+		case mimeStatus.Added && mimeStatus.EffectivePath != "":
+			fmt.Printf("    %s: added as an alternative to %s in %s\n", mimeStatus.MimeType, mimeStatus.EffectiveDesktopID, mimeStatus.EffectivePath)
+		case mimeStatus.Added:
+			fmt.Printf("    %s: added as an alternative\n", mimeStatus.MimeType)
+		case mimeStatus.EffectivePath != "":
+			fmt.Printf("    %s: not registered (%s in %s)\n", mimeStatus.MimeType, mimeStatus.EffectiveDesktopID, mimeStatus.EffectivePath)
+		default:
+			fmt.Printf("    %s: not registered\n", mimeStatus.MimeType)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatus(t *testing.T) {
+	home := setupXDG(t)
+	t.Setenv("XDG_CURRENT_DESKTOP", "GNOME")
+	pdfEntry := mediaGroups["pdf"][0]
+	writeTestFile(t, desktopEntryPath(pdfEntry), desktopEntryFor(pdfEntry).String())
+	writeTestFile(t, path.Join(home, ".config/mimeapps.list"), "[Default Applications]\napplication/pdf=wsl-open-proxy-pdf.desktop\ntext/html=wsl-open-proxy-html.desktop\n")
+	writeTestFile(t, path.Join(home, ".config/gnome-mimeapps.list"), "[Default Applications]\ntext/html=firefox.desktop\n")
+	writeTestFile(t, path.Join(home, "usr/share/applications/firefox.desktop"), "[Desktop Entry]\nType=Application\nName=Firefox\nExec=firefox %u\n")
+
+	report, err := status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Exe.Installed {
+		t.Errorf("Exe.Installed = true, want false")
+	}
+
+	var pdf, html desktopEntryStatus
+	for _, groupStatus := range report.MediaGroups {
+		switch groupStatus.Name {
+		case "pdf":
+			pdf = groupStatus.DesktopEntries[0]
+		case "html":
+			html = groupStatus.DesktopEntries[0]
+		}
+	}
+	if !pdf.Exists || !pdf.UpToDate {
+		t.Errorf("pdf: Exists = %v, UpToDate = %v, want true, true", pdf.Exists, pdf.UpToDate)
+	}
+	wantPDF := mimeTypeStatus{
+		MimeType:           "application/pdf",
+		Registered:         true,
+		EffectivePath:      path.Join(home, ".config/mimeapps.list"),
+		EffectiveDesktopID: "wsl-open-proxy-pdf.desktop",
+		Effective:          true,
+	}
+	if diff := cmp.Diff(wantPDF, pdf.MimeTypes[0]); diff != "" {
+		t.Errorf("pdf mime type status mismatch (-want +got):\n%s", diff)
+	}
+	if html.Exists {
+		t.Errorf("html: Exists = true, want false")
+	}
+	wantHTML := mimeTypeStatus{
+		MimeType:           "text/html",
+		Registered:         true,
+		EffectivePath:      path.Join(home, ".config/gnome-mimeapps.list"),
+		EffectiveDesktopID: "firefox.desktop",
+		Effective:          false,
+	}
+	if diff := cmp.Diff(wantHTML, html.MimeTypes[0]); diff != "" {
+		t.Errorf("html mime type status mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStatusReportsManifestEntries(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "")
+	if err := os.MkdirAll(path.Join(home, ".local/share/applications"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Like --ext .docx
+	docxMimeType := "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	w := &fileWriter{yes: true}
+	if err := install(context.Background(), w, false, []mimeEntry{{".docx", []string{docxMimeType}}}, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Extensions) != 1 {
+		t.Fatalf("len(Extensions) = %d, want 1", len(report.Extensions))
+	}
+	docx := report.Extensions[0]
+	if docx.Extension != ".docx" || !docx.Exists || !docx.UpToDate {
+		t.Errorf("docx: Extension = %q, Exists = %v, UpToDate = %v, want .docx, true, true", docx.Extension, docx.Exists, docx.UpToDate)
+	}
+	if len(docx.MimeTypes) != 1 || !docx.MimeTypes[0].Effective {
+		t.Errorf("docx: MimeTypes = %+v, want %s in effect", docx.MimeTypes, docxMimeType)
+	}
+
+	for _, groupStatus := range report.MediaGroups {
+		if groupStatus.Name == "scheme" && (!groupStatus.OptIn || groupStatus.Installed) {
+			t.Errorf("scheme: OptIn = %v, Installed = %v, want true, false", groupStatus.OptIn, groupStatus.Installed)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+	"github.com/spf13/cobra"
+)
+
+func newUninstallCmd() *cobra.Command {
+	removeExe := false
+	desktop := ""
+	var mediaGroupNames []string
+	var extensions []string
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove desktop entries and mime associations registered by setup-wsl-open",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("too many arguments")
+			}
+			mimeEntries, err := uninstallMimeEntries(mediaGroupNames, extensions)
+			if err != nil {
+				return err
+			}
+			w, err := fileWriterFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return uninstall(cmd.Context(), w, mimeEntries, removeExe, desktop)
+		},
+	}
+	cmd.Flags().BoolVar(&removeExe, "remove-exe", removeExe, "Also remove wsl-open-proxy.exe if no desktop entry uses it anymore")
+	cmd.Flags().StringSliceVarP(&mediaGroupNames, "type", "t", mediaGroupNames, fmt.Sprintf("Media groups to uninstall, repeated or comma-separated (Any of: %v, or those defined in groups.ini; default: everything installed)", sortedMediaGroupNames()))
+	cmd.Flags().StringSliceVar(&extensions, "ext", extensions, "Extensions to uninstall in addition to the media groups (like .docx)")
+	cmd.Flags().StringVar(&desktop, "desktop", desktop, "Unregister from the mimeapps.list specific to the desktop environment (like GNOME or KDE)")
+	addFileWriterFlags(cmd)
+	return cmd
+}
+
+// uninstallMimeEntries returns the entries to uninstall. Without media groups
+// or extensions, everything recorded in the manifest is uninstalled, or all
+// the media groups if there is no manifest. groups.ini is read only if the
+// media groups are needed, so that a broken one does not get in the way.
+func uninstallMimeEntries(mediaGroupNames []string, extensions []string) ([]mimeEntry, error) {
+	if len(mediaGroupNames) > 0 || len(extensions) > 0 {
+		if err := loadUserMediaGroups(); err != nil {
+			return nil, err
+		}
+		return collectMimeEntries(mediaGroupNames, extensions)
+	}
+	m, err := loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	if mimeEntries := m.mimeEntries(); len(mimeEntries) > 0 {
+		return mimeEntries, nil
+	}
+	// Installed by an older setup-wsl-open, or nothing is installed
+	if err := loadUserMediaGroups(); err != nil {
+		return nil, err
+	}
+	return collectMimeEntries(sortedMediaGroupNames(), nil)
+}
+
+func uninstall(ctx context.Context, w *fileWriter, mimeEntries []mimeEntry, removeExe bool, desktop string) error {
+	_ = ctx
+
+	m, err := loadManifest()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Unregistering mime associations...\n")
+	target, err := targetMimeAppsListPath(desktop)
+	if err != nil {
+		return err
+	}
+	mimeAppsListPaths := []string{target}
+	// Also look at the files registered with other --desktop options
+	for _, a := range m.Associations {
+		if !slices.Contains(mimeAppsListPaths, a.File) && slices.ContainsFunc(mimeEntries, func(e mimeEntry) bool {
+			return desktopEntryID(e) == a.DesktopID
+		}) {
+			mimeAppsListPaths = append(mimeAppsListPaths, a.File)
+		}
+	}
+	for _, mimeAppsListPath := range mimeAppsListPaths {
+		if err := unregisterAssociations(w, m, mimeAppsListPath, mimeEntries); err != nil {
+			return err
+		}
+	}
+
+	for _, mimeEntry := range mimeEntries {
+		desktopEntryPath := desktopEntryPath(mimeEntry)
+		if err := confirmRemovingModified(w, desktopEntryPath, m.desktopEntry(desktopEntryPath)); err != nil {
+			return err
+		}
+		if err := w.removeFile(desktopEntryPath); err != nil {
+			return err
+		}
+		m.forgetDesktopEntry(desktopEntryPath)
+	}
+	if err := m.save(w); err != nil {
+		return err
+	}
+	if err := updateMimeInfoCache(w); err != nil {
+		return err
+	}
+
+	if removeExe {
+		remaining, err := remainingDesktopEntries(w, mimeEntries)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			fmt.Fprintf(os.Stderr, "Keeping wsl-open-proxy.exe as it is still used by %s\n", strings.Join(remaining, ", "))
+		} else {
+			exeInstallPath := exeInstallPath()
+			if m.Exe != nil && m.Exe.Path == exeInstallPath {
+				currentHash, err := fileSHA256(exeInstallPath)
+				if err != nil {
+					return err
+				}
+				if currentHash != "" && currentHash != m.Exe.SHA256 {
+					fmt.Fprintf(os.Stderr, "%s has been changed since it was installed\n", exeInstallPath)
+					if err := w.confirm(fmt.Sprintf("Removing %s", exeInstallPath)); err != nil {
+						return err
+					}
+				}
+			}
+			if err := w.removeFile(exeInstallPath); err != nil {
+				return err
+			}
+			m.Exe = nil
+			if err := m.save(w); err != nil {
+				return err
+			}
+		}
+	}
+	return w.finish()
+}
+
+// unregisterAssociations removes the desktop entries from the mimeapps.list,
+// restoring the default applications they replaced.
+func unregisterAssociations(w *fileWriter, m *manifest, mimeAppsListPath string, mimeEntries []mimeEntry) error {
+	mimeAppsListText, err := os.ReadFile(mimeAppsListPath)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to read %s", mimeAppsListPath)
+	}
+	mimeAppsList := parseConfigFile(mimeAppsListPath, mimeAppsListText)
+	if addedAssociations, ok := mimeAppsList.Groups["Added Associations"]; ok {
+		mimeTypes := slices.Collect(maps.Keys(addedAssociations.Entries))
+		for _, mimeEntry := range mimeEntries {
+			for _, mimeType := range mimeTypes {
+				addedAssociations.RemoveFromList(mimeType, desktopEntryID(mimeEntry))
+			}
+		}
+		if len(addedAssociations.Entries) == 0 {
+			mimeAppsList.DeleteGroup("Added Associations")
+		}
+	}
+	if defaultApplications, ok := mimeAppsList.Groups["Default Applications"]; ok {
+		// Look at all the MIME types as the association may have been
+		// registered with MIME types from shared-mime-info
+		mimeTypes := slices.Collect(maps.Keys(defaultApplications.Entries))
+		for _, mimeEntry := range mimeEntries {
+			desktopID := desktopEntryID(mimeEntry)
+			for _, mimeType := range mimeTypes {
+				var previousValue *string
+				if a := m.association(mimeAppsListPath, "Default Applications", mimeType); a != nil && a.DesktopID == desktopID {
+					previousValue = a.PreviousValue
+				}
+				removeDefaultApplication(defaultApplications, mimeType, desktopID, previousValue)
+			}
+		}
+		if len(defaultApplications.Entries) == 0 {
+			mimeAppsList.DeleteGroup("Default Applications")
+		}
+	}
+	for _, mimeEntry := range mimeEntries {
+		desktopID := desktopEntryID(mimeEntry)
+		m.Associations = slices.DeleteFunc(m.Associations, func(a manifestAssociation) bool {
+			return a.File == mimeAppsListPath && a.DesktopID == desktopID
+		})
+	}
+	if err := w.writeFileWithConfirmation(
+		mimeAppsListPath,
+		[]byte(mimeAppsList.String()),
+	); err != nil {
+		return errors.Wrap(err, "failed to mime association file")
+	}
+	return nil
+}
+
+// confirmRemovingModified asks before removing a file which has been edited
+// since setup-wsl-open wrote it. Files not in the manifest are removed as
+// before, since they may come from an older setup-wsl-open.
+func confirmRemovingModified(w *fileWriter, filePath string, recorded *manifestDesktopEntry) error {
+	if recorded == nil {
+		return nil
+	}
+	currentHash, err := fileSHA256(filePath)
+	if err != nil {
+		return err
+	}
+	if currentHash == "" || currentHash == recorded.SHA256 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%s has been changed since it was installed\n", filePath)
+	return w.confirm(fmt.Sprintf("Removing %s", filePath))
+}
+
+// remainingDesktopEntries lists the desktop entries of wsl-open-proxy that
+// are left after uninstallation.
+func remainingDesktopEntries(w *fileWriter, mimeEntries []mimeEntry) ([]string, error) {
+	desktopEntryPaths, err := filepath.Glob(path.Join(xdg.DataHome, "applications", "wsl-open-proxy-*.desktop"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list remaining desktop entries")
+	}
+	if !w.dryRun {
+		return desktopEntryPaths, nil
+	}
+	// In dry-run mode, the files to be removed are still there
+	removed := map[string]bool{}
+	for _, mimeEntry := range mimeEntries {
+		removed[desktopEntryPath(mimeEntry)] = true
+	}
+	var remaining []string
+	for _, desktopEntryPath := range desktopEntryPaths {
+		if !removed[desktopEntryPath] {
+			remaining = append(remaining, desktopEntryPath)
+		}
+	}
+	return remaining, nil
+}
+
+// removeDefaultApplication removes the desktop file ID from the list of
+// default applications for the MIME type, leaving other IDs as they are.
+// If no ID is left, the value replaced on installation is put back, if any.
+func removeDefaultApplication(defaultApplications *xdgini.ConfigGroup, mimeType string, desktopID string, previousValue *string) {
+	entry, ok := defaultApplications.Entries[mimeType]
+	if !ok {
+		return
+	}
+	ids := xdgini.SplitList(entry.Value)
+	if previousValue != nil && slices.Equal(ids, []string{desktopID}) {
+		entry.Value = *previousValue
+		return
+	}
+	defaultApplications.RemoveFromList(mimeType, desktopID)
+}
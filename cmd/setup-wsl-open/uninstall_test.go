@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestRemoveDefaultApplication(t *testing.T) {
+	evince := "evince.desktop"
+	testcases := []struct {
+		name          string
+		input         string
+		previousValue *string
+		output        string
+	}{
+		{
+			name:   "sole handler",
+			input:  "[Default Applications]\ntext/html=wsl-open-proxy-html.desktop\napplication/pdf=evince.desktop\n",
+			output: "[Default Applications]\napplication/pdf=evince.desktop\n",
+		},
+		{
+			name:   "one of handlers",
+			input:  "[Default Applications]\ntext/html=firefox.desktop;wsl-open-proxy-html.desktop;\n",
+			output: "[Default Applications]\ntext/html=firefox.desktop;\n",
+		},
+		{
+			name:   "other handler",
+			input:  "[Default Applications]\ntext/html=firefox.desktop\n",
+			output: "[Default Applications]\ntext/html=firefox.desktop\n",
+		},
+		{
+			name:          "restores previous handler",
+			input:         "[Default Applications]\ntext/html=wsl-open-proxy-html.desktop\n",
+			previousValue: &evince,
+			output:        "[Default Applications]\ntext/html=evince.desktop\n",
+		},
+		{
+			name:          "keeps handlers added later",
+			input:         "[Default Applications]\ntext/html=firefox.desktop;wsl-open-proxy-html.desktop\n",
+			previousValue: &evince,
+			output:        "[Default Applications]\ntext/html=firefox.desktop\n",
+		},
+		{
+			name:          "overridden by user",
+			input:         "[Default Applications]\ntext/html=firefox.desktop\n",
+			previousValue: &evince,
+			output:        "[Default Applications]\ntext/html=firefox.desktop\n",
+		},
+		{
+			name:   "keeps comments",
+			input:  "[Default Applications]\n# Browser\ntext/html=wsl-open-proxy-html.desktop\n\n[Added Associations]\n",
+			output: "[Default Applications]\n# Browser\n\n[Added Associations]\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			removeDefaultApplication(config.Groups["Default Applications"], "text/html", "wsl-open-proxy-html.desktop", tc.previousValue)
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUninstallFromManifest(t *testing.T) {
+	home := setupXDG(t)
+	writeTestFile(t, exeInstallPath(), "exe")
+	mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+	writeTestFile(t, mimeAppsListPath, "[Default Applications]\ntext/plain=gedit.desktop\n")
+
+	// Like --ext .docx
+	docxMimeType := "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	w := &fileWriter{yes: true}
+	if err := install(context.Background(), w, false, []mimeEntry{{".docx", []string{docxMimeType}}}, associationModeDefault, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	mimeEntries, err := uninstallMimeEntries(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mimeEntry{{".docx", []string{docxMimeType}}}
+	if diff := cmp.Diff(want, mimeEntries, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+		t.Errorf("uninstallMimeEntries() mismatch (-want +got):\n%s", diff)
+	}
+	if err := uninstall(context.Background(), w, mimeEntries, true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path.Join(home, ".local/share/applications/wsl-open-proxy-docx.desktop")); !os.IsNotExist(err) {
+		t.Errorf("desktop entry for .docx is left: %v", err)
+	}
+	if _, err := os.Stat(exeInstallPath()); !os.IsNotExist(err) {
+		t.Errorf("wsl-open-proxy.exe is left: %v", err)
+	}
+	content, err := os.ReadFile(mimeAppsListPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[Default Applications]\ntext/plain=gedit.desktop\n", string(content)); diff != "" {
+		t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUninstallWithoutManifest(t *testing.T) {
+	setupXDG(t)
+	mimeEntries, err := uninstallMimeEntries(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := collectMimeEntries(sortedMediaGroupNames(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, mimeEntries, cmp.AllowUnexported(mimeEntry{})); diff != "" {
+		t.Errorf("uninstallMimeEntries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInstallUninstallRoundTrip(t *testing.T) {
+	testcases := []struct {
+		name    string
+		mode    string
+		initial *string
+	}{
+		{"missing file", associationModeDefault, nil},
+		{"empty file", associationModeDefault, new(string)},
+		{"missing file, added", associationModeAdded, nil},
+		{"empty file, added", associationModeAdded, new(string)},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := setupXDG(t)
+			writeTestFile(t, exeInstallPath(), "")
+			mimeAppsListPath := path.Join(home, ".config/mimeapps.list")
+			if tc.initial != nil {
+				writeTestFile(t, mimeAppsListPath, *tc.initial)
+			}
+
+			w := &fileWriter{yes: true}
+			mimeEntries, err := collectMimeEntries([]string{"pdf"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := install(context.Background(), w, false, mimeEntries, tc.mode, ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := uninstall(context.Background(), w, mimeEntries, false, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := os.ReadFile(mimeAppsListPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff("", string(content)); diff != "" {
+				t.Errorf("mimeapps.list mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate FILE...",
+		Short: "Check desktop entries against the Desktop Entry Specification",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return validate(args)
+		},
+	}
+	return cmd
+}
+
+// validate prints the problems found in the desktop entries and fails if
+// any of them is an error.
+func validate(filePaths []string) error {
+	numErrors := 0
+	for _, filePath := range filePaths {
+		text, err := os.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		for _, d := range xdgini.ValidateDesktopEntry(xdgini.ParseConfig(string(text))) {
+			if d.Line == 0 {
+				fmt.Printf("%s: %s\n", filePath, d)
+			} else {
+				fmt.Printf("%s:%s\n", filePath, d)
+			}
+			if d.Severity == xdgini.SeverityError {
+				numErrors++
+			}
+		}
+	}
+	if numErrors > 0 {
+		return errors.Errorf("%d error(s) found", numErrors)
+	}
+	return nil
+}
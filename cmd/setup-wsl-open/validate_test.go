@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	validPath := path.Join(dir, "valid.desktop")
+	writeTestFile(t, validPath, desktopEntryFor(mediaGroups["pdf"][0]).String())
+	warningPath := path.Join(dir, "warning.desktop")
+	writeTestFile(t, warningPath, "[Desktop Entry]\nType=Application\nName=Foo\nExec=foo %f\nMimeType=application/pdf\n")
+	invalidPath := path.Join(dir, "invalid.desktop")
+	writeTestFile(t, invalidPath, "[Desktop Entry]\nType=Application\nExec=foo %f %u\n")
+
+	if err := validate([]string{validPath}); err != nil {
+		t.Errorf("validate(valid.desktop) error: %v", err)
+	}
+	if err := validate([]string{validPath, warningPath}); err != nil {
+		t.Errorf("validate(warning.desktop) error: %v", err)
+	}
+	if err := validate([]string{validPath, invalidPath}); err == nil || err.Error() != "2 error(s) found" {
+		t.Errorf("validate(invalid.desktop) error = %v, want 2 error(s) found", err)
+	}
+	if err := validate([]string{path.Join(dir, "missing.desktop")}); err == nil {
+		t.Errorf("validate(missing.desktop) succeeded")
+	}
+}
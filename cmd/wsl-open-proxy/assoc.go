@@ -0,0 +1,139 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/wincmd"
+)
+
+const (
+	ASSOCF_NONE                 = 0x00000000
+	ASSOCF_INIT_NOREMAPCLSID    = 0x00000001
+	ASSOCF_INIT_BYEXENAME       = 0x00000002
+	ASSOCF_OPEN_BYEXENAME       = 0x00000002
+	ASSOCF_INIT_DEFAULTTOSTAR   = 0x00000004
+	ASSOCF_INIT_DEFAULTTOFOLDER = 0x00000008
+	ASSOCF_NOUSERSETTINGS       = 0x00000010
+	ASSOCF_NOTRUNCATE           = 0x00000020
+	ASSOCF_VERIFY               = 0x00000040
+	ASSOCF_REMAPRUNDLL          = 0x00000080
+	ASSOCF_NOFIXUPS             = 0x00000100
+	ASSOCF_IGNOREBASECLASS      = 0x00000200
+	ASSOCF_INIT_IGNOREUNKNOWN   = 0x00000400
+	ASSOCF_INIT_FIXED_PROGID    = 0x00000800
+	ASSOCF_IS_PROTOCOL          = 0x00001000
+	ASSOCF_INIT_FOR_FILE        = 0x00002000
+	ASSOCF_IS_FULL_URI          = 0x00004000
+	ASSOCF_PER_MACHINE_ONLY     = 0x00008000
+	ASSOCF_APP_TO_APP           = 0x00010000
+)
+
+const (
+	ASSOCSTR_COMMAND = iota + 1
+	ASSOCSTR_EXECUTABLE
+	ASSOCSTR_FRIENDLYDOCNAME
+	ASSOCSTR_FRIENDLYAPPNAME
+	ASSOCSTR_NOOPEN
+	ASSOCSTR_SHELLNEWVALUE
+	ASSOCSTR_DDECOMMAND
+	ASSOCSTR_DDEIFEXEC
+	ASSOCSTR_DDEAPPLICATION
+	ASSOCSTR_DDETOPIC
+	ASSOCSTR_INFOTIP
+	ASSOCSTR_QUICKTIP
+	ASSOCSTR_TILEINFO
+	ASSOCSTR_CONTENTTYPE
+	ASSOCSTR_DEFAULTICON
+	ASSOCSTR_SHELLEXTENSION
+	ASSOCSTR_DROPTARGET
+	ASSOCSTR_DELEGATEEXECUTE
+	ASSOCSTR_SUPPORTED_URI_PROTOCOLS
+	ASSOCSTR_PROGID
+	ASSOCSTR_APPID
+	ASSOCSTR_APPPUBLISHER
+	ASSOCSTR_APPICONREFERENCE
+	ASSOCSTR_MAX
+)
+
+// errNoAssociation is returned by assocQuerier if the requested string is
+// not registered, like HRESULT_FROM_WIN32(ERROR_NO_ASSOCIATION).
+var errNoAssociation = errors.New("no association")
+
+// assocQuerier looks up strings associated with file extensions and URL
+// schemes, like AssocQueryStringW. It is faked in tests.
+type assocQuerier interface {
+	QueryString(flags int32, str int32, assoc string, extra string) (string, error)
+}
+
+// association identifies the class to look up: a file extension like ".pdf",
+// or a URL scheme like "mailto" with ASSOCF_IS_PROTOCOL.
+type association struct {
+	flags int32
+	name  string
+}
+
+func extensionAssociation(ext string) association {
+	return association{flags: ASSOCF_NONE, name: ext}
+}
+
+func schemeAssociation(scheme string) association {
+	return association{flags: ASSOCF_IS_PROTOCOL, name: scheme}
+}
+
+func (a association) isProtocol() bool {
+	return a.flags&ASSOCF_IS_PROTOCOL != 0
+}
+
+// resolveCommand returns the command line that opens the file with the
+// application associated with the class. The command registered for the
+// "open" verb is used, and the executable is used only if no command exists.
+func resolveCommand(q assocQuerier, a association, file string) (string, error) {
+	template, err := q.QueryString(a.flags, ASSOCSTR_COMMAND, a.name, "open")
+	if err != nil && !errors.Is(err, errNoAssociation) {
+		return "", errors.Wrapf(err, "error getting command for %s", a.name)
+	} else if err == nil && template != "" {
+		return wincmd.Expand(template, []string{file}, nil), nil
+	}
+
+	exe, err := q.QueryString(a.flags, ASSOCSTR_EXECUTABLE, a.name, "open")
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting executable for %s", a.name)
+	}
+	// ASSOCSTR_EXECUTABLE gives a bare path, which may contain spaces
+	return wincmd.Expand(wincmd.EscapeArg(exe), []string{file}, nil), nil
+}
+
+// hasAssociation reports whether an application is registered for the
+// class, either with a command or with a DelegateExecute handler.
+func hasAssociation(q assocQuerier, a association) bool {
+	for _, str := range []int32{ASSOCSTR_COMMAND, ASSOCSTR_DELEGATEEXECUTE} {
+		if value, err := q.QueryString(a.flags, str, a.name, "open"); err == nil && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAssociation chooses the class to open the file or URL with. URLs are
+// opened by the handler of their scheme if there is one, and by the given
+// extension otherwise; files are opened by the given extension or their own.
+func resolveAssociation(q assocQuerier, file string, ext string, isURL bool) (association, error) {
+	if isURL {
+		scheme := urlScheme(file)
+		if scheme == "" {
+			return association{}, errors.Errorf("No URL scheme found in %s", file)
+		}
+		if a := schemeAssociation(scheme); hasAssociation(q, a) || ext == "" {
+			return a, nil
+		}
+		return extensionAssociation(ext), nil
+	}
+	if ext == "" {
+		ext = filepath.Ext(file)
+	}
+	if ext == "" {
+		return association{}, errors.New("No file extension found")
+	}
+	return extensionAssociation(ext), nil
+}
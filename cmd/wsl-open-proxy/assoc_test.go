@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeAssocKey struct {
+	flags int32
+	str   int32
+	assoc string
+	extra string
+}
+
+// fakeRegistry is an assocQuerier serving fixed strings.
+type fakeRegistry map[fakeAssocKey]string
+
+func (r fakeRegistry) QueryString(flags int32, str int32, assoc string, extra string) (string, error) {
+	if value, ok := r[fakeAssocKey{flags, str, assoc, extra}]; ok {
+		return value, nil
+	}
+	return "", errNoAssociation
+}
+
+type brokenRegistry struct{}
+
+func (brokenRegistry) QueryString(flags int32, str int32, assoc string, extra string) (string, error) {
+	return "", errors.New("access denied")
+}
+
+func TestAssocStrConstants(t *testing.T) {
+	if ASSOCSTR_COMMAND != 1 || ASSOCSTR_EXECUTABLE != 2 || ASSOCSTR_PROGID != 20 || ASSOCSTR_MAX != 24 {
+		t.Errorf("unexpected ASSOCSTR values: COMMAND=%d, EXECUTABLE=%d, PROGID=%d, MAX=%d", ASSOCSTR_COMMAND, ASSOCSTR_EXECUTABLE, ASSOCSTR_PROGID, ASSOCSTR_MAX)
+	}
+}
+
+func TestResolveCommand(t *testing.T) {
+	registry := fakeRegistry{
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".pdf", "open"}:    `"C:\Program Files\Adobe\Acrobat.exe" /n "%1"`,
+		{ASSOCF_NONE, ASSOCSTR_EXECUTABLE, ".pdf", "open"}: `C:\Program Files\Adobe\Acrobat.exe`,
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".png", "open"}:    `C:\Windows\System32\rundll32.exe "C:\Program Files\Windows Photo Viewer\PhotoViewer.dll", ImageView_Fullscreen %1`,
+		{ASSOCF_NONE, ASSOCSTR_EXECUTABLE, ".png", "open"}: `C:\Windows\System32\rundll32.exe`,
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".txt", "open"}:    ``,
+		{ASSOCF_NONE, ASSOCSTR_EXECUTABLE, ".txt", "open"}: `C:\Program Files\Notepad++\notepad++.exe`,
+		{ASSOCF_NONE, ASSOCSTR_EXECUTABLE, ".log", "open"}: `C:\Windows\notepad.exe`,
+	}
+	testcases := []struct {
+		ext  string
+		file string
+		cmd  string
+	}{
+		{".pdf", `C:\Users\me\a b.pdf`, `"C:\Program Files\Adobe\Acrobat.exe" /n "C:\Users\me\a b.pdf"`},
+		{".png", `C:\a.png`, `C:\Windows\System32\rundll32.exe "C:\Program Files\Windows Photo Viewer\PhotoViewer.dll", ImageView_Fullscreen C:\a.png`},
+		// Empty command
+		{".txt", `C:\a b.txt`, `"C:\Program Files\Notepad++\notepad++.exe" "C:\a b.txt"`},
+		// No command
+		{".log", `C:\a.log`, `C:\Windows\notepad.exe C:\a.log`},
+	}
+	for _, tc := range testcases {
+		cmd, err := resolveCommand(registry, extensionAssociation(tc.ext), tc.file)
+		if err != nil {
+			t.Errorf("resolveCommand(%q) error: %v", tc.ext, err)
+		} else if cmd != tc.cmd {
+			t.Errorf("resolveCommand(%q) = %s, want %s", tc.ext, cmd, tc.cmd)
+		}
+	}
+
+	if _, err := resolveCommand(registry, extensionAssociation(".unknown"), `C:\a.unknown`); !errors.Is(err, errNoAssociation) {
+		t.Errorf("resolveCommand(.unknown) error = %v, want errNoAssociation", err)
+	}
+	// Errors other than missing associations are not hidden by the fallback
+	if _, err := resolveCommand(brokenRegistry{}, extensionAssociation(".pdf"), `C:\a.pdf`); err == nil {
+		t.Errorf("resolveCommand() with a broken registry succeeded")
+	}
+}
+
+func TestResolveAssociation(t *testing.T) {
+	registry := fakeRegistry{
+		{ASSOCF_IS_PROTOCOL, ASSOCSTR_COMMAND, "mailto", "open"}:              `"C:\Program Files\Thunderbird\thunderbird.exe" -osint -compose "%1"`,
+		{ASSOCF_IS_PROTOCOL, ASSOCSTR_DELEGATEEXECUTE, "ms-settings", "open"}: `{C6B9B4C3-0C2A-4D1C-8E4C-6E9B2D0B1B1B}`,
+		{ASSOCF_IS_PROTOCOL, ASSOCSTR_COMMAND, "https", "open"}:               `"C:\Program Files\Mozilla Firefox\firefox.exe" -osint -url "%1"`,
+	}
+	testcases := []struct {
+		name  string
+		file  string
+		ext   string
+		isURL bool
+		want  association
+	}{
+		{"file", "/home/me/a.pdf", "", false, extensionAssociation(".pdf")},
+		{"file with --ext", "/home/me/README", ".txt", false, extensionAssociation(".txt")},
+		{"mailto", "mailto:me@example.com", ".html", true, schemeAssociation("mailto")},
+		{"DelegateExecute", "ms-settings:display", ".html", true, schemeAssociation("ms-settings")},
+		{"uppercase scheme", "HTTPS://example.com/a.pdf", ".html", true, schemeAssociation("https")},
+		{"unknown scheme with --ext", "zoommtg://zoom.us/join", ".html", true, extensionAssociation(".html")},
+		{"unknown scheme without --ext", "zoommtg://zoom.us/join", "", true, schemeAssociation("zoommtg")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveAssociation(registry, tc.file, tc.ext, tc.isURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("resolveAssociation() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := resolveAssociation(registry, "/home/me/README", "", false); err == nil {
+		t.Errorf("resolveAssociation() succeeded without extension")
+	}
+	if _, err := resolveAssociation(registry, "/home/me/README", "", true); err == nil {
+		t.Errorf("resolveAssociation() succeeded without scheme")
+	}
+}
+
+func TestIsLikelyURL(t *testing.T) {
+	testcases := []struct {
+		s      string
+		isURL  bool
+		scheme string
+	}{
+		{"https://example.com/", true, "https"},
+		{"vscode://file/home/me/a.go", true, "vscode"},
+		{"MAILTO:me@example.com", true, "mailto"},
+		{"ms-settings:display", true, "ms-settings"},
+		{"/home/me/a.pdf", false, ""},
+		{"/home/me/http://a.pdf", false, ""},
+		{"foo:bar", false, "foo"},
+	}
+	for _, tc := range testcases {
+		if got := isLikelyURL(tc.s); got != tc.isURL {
+			t.Errorf("isLikelyURL(%q) = %v, want %v", tc.s, got, tc.isURL)
+		}
+		if got := urlScheme(tc.s); got != tc.scheme {
+			t.Errorf("urlScheme(%q) = %q, want %q", tc.s, got, tc.scheme)
+		}
+	}
+}
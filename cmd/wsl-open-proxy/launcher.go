@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// Open with ShellExecuteExW, which also handles packaged apps and
+	// DelegateExecute handlers
+	launcherShell = "shell"
+	// Start the registered command with CreateProcess
+	launcherCreateProcess = "createprocess"
+	// Use CreateProcess if a usable command is registered, and ShellExecuteExW
+	// otherwise or if CreateProcess fails
+	launcherAuto = "auto"
+)
+
+var launcherModes = []string{launcherAuto, launcherShell, launcherCreateProcess}
+
+// launcher starts applications. It is faked in tests.
+type launcher interface {
+	// ShellExecute opens the file or URL with the "open" verb of the class
+	// (a file extension like ".pdf"), or the class the shell finds for it if
+	// empty.
+	ShellExecute(file string, class string) error
+	// CreateProcess starts the command line.
+	CreateProcess(cmdline string) error
+}
+
+// launch opens the file with the application associated with the class,
+// using the strategy selected by mode.
+func launch(q assocQuerier, l launcher, mode string, a association, file string) error {
+	// The shell finds the scheme handler of URLs itself, following the
+	// choice of the user
+	class := a.name
+	if a.isProtocol() {
+		class = ""
+	}
+	switch mode {
+	case launcherShell:
+		return l.ShellExecute(file, class)
+	case launcherCreateProcess:
+		cmd, err := resolveCommand(q, a, file)
+		if err != nil {
+			return err
+		}
+		return l.CreateProcess(cmd)
+	}
+
+	// Packaged apps register a COM handler instead of a usable command
+	if delegate, err := q.QueryString(a.flags, ASSOCSTR_DELEGATEEXECUTE, a.name, "open"); err == nil && delegate != "" {
+		return l.ShellExecute(file, class)
+	}
+	cmd, err := resolveCommand(q, a, file)
+	if errors.Is(err, errNoAssociation) {
+		return l.ShellExecute(file, class)
+	} else if err != nil {
+		return err
+	}
+	if err := l.CreateProcess(cmd); err != nil {
+		if shellErr := l.ShellExecute(file, class); shellErr != nil {
+			return errors.Errorf("%v; falling back to ShellExecuteEx also failed: %v", err, shellErr)
+		}
+	}
+	return nil
+}
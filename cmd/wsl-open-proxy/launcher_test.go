@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeLauncher records the calls, failing CreateProcess for the commands in
+// failing.
+type fakeLauncher struct {
+	calls   []string
+	failing map[string]bool
+}
+
+func (l *fakeLauncher) ShellExecute(file string, class string) error {
+	l.calls = append(l.calls, "shell "+class+" "+file)
+	return nil
+}
+
+func (l *fakeLauncher) CreateProcess(cmdline string) error {
+	l.calls = append(l.calls, "createprocess "+cmdline)
+	if l.failing[cmdline] {
+		return errors.New("file not found")
+	}
+	return nil
+}
+
+func TestLaunch(t *testing.T) {
+	registry := fakeRegistry{
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".pdf", "open"}:         `"C:\Acrobat.exe" /n "%1"`,
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".png", "open"}:         `"C:\Windows\explorer.exe" /open "%1"`,
+		{ASSOCF_NONE, ASSOCSTR_DELEGATEEXECUTE, ".png", "open"}: `{4ED3A719-CEA8-4BD9-910D-E252F997AFC2}`,
+		{ASSOCF_NONE, ASSOCSTR_COMMAND, ".txt", "open"}:         `C:\Missing\editor.exe "%1"`,
+	}
+	testcases := []struct {
+		name  string
+		mode  string
+		ext   string
+		calls []string
+	}{
+		{
+			name:  "auto with a command",
+			mode:  launcherAuto,
+			ext:   ".pdf",
+			calls: []string{`createprocess "C:\Acrobat.exe" /n "C:\a.pdf"`},
+		},
+		{
+			name:  "auto with DelegateExecute",
+			mode:  launcherAuto,
+			ext:   ".png",
+			calls: []string{`shell .png C:\a.pdf`},
+		},
+		{
+			name:  "auto without association",
+			mode:  launcherAuto,
+			ext:   ".mp4",
+			calls: []string{`shell .mp4 C:\a.pdf`},
+		},
+		{
+			name: "auto falls back to shell",
+			mode: launcherAuto,
+			ext:  ".txt",
+			calls: []string{
+				`createprocess C:\Missing\editor.exe "C:\a.pdf"`,
+				`shell .txt C:\a.pdf`,
+			},
+		},
+		{
+			name:  "shell",
+			mode:  launcherShell,
+			ext:   ".pdf",
+			calls: []string{`shell .pdf C:\a.pdf`},
+		},
+		{
+			name:  "createprocess",
+			mode:  launcherCreateProcess,
+			ext:   ".png",
+			calls: []string{`createprocess "C:\Windows\explorer.exe" /open "C:\a.pdf"`},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &fakeLauncher{failing: map[string]bool{`C:\Missing\editor.exe "C:\a.pdf"`: true}}
+			if err := launch(registry, l, tc.mode, extensionAssociation(tc.ext), `C:\a.pdf`); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.calls, l.calls); diff != "" {
+				t.Errorf("launcher calls mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// URLs are given to the shell without a class
+	l := &fakeLauncher{}
+	if err := launch(registry, l, launcherShell, schemeAssociation("mailto"), "mailto:me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"shell  mailto:me@example.com"}, l.calls); diff != "" {
+		t.Errorf("launcher calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// createprocess does not fall back to shell
+	l = &fakeLauncher{}
+	if err := launch(registry, l, launcherCreateProcess, extensionAssociation(".mp4"), `C:\a.mp4`); !errors.Is(err, errNoAssociation) {
+		t.Errorf("launch() error = %v, want errNoAssociation", err)
+	}
+	if len(l.calls) > 0 {
+		t.Errorf("launcher called: %v", l.calls)
+	}
+}
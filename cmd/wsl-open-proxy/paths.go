@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/wslpath"
+)
+
+// toWindowsPath translates the Linux path in the distribution to a Windows
+// path. It reads the configuration of the distribution through its UNC path,
+// and runs wslpath only for paths it cannot translate itself. With verbose,
+// the reason for running wslpath is reported on stderr.
+func toWindowsPath(ctx context.Context, file string, distro string, verbose bool) (string, error) {
+	if distro == "" {
+		// Only available if shared through WSLENV
+		distro = os.Getenv("WSL_DISTRO_NAME")
+	}
+	if distro != "" && path.IsAbs(file) {
+		wFile, err := translatePath(file, distro)
+		if err == nil {
+			return wFile, nil
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Falling back to wslpath: %v\n", err)
+		}
+	} else if verbose && distro == "" {
+		fmt.Fprintf(os.Stderr, "Falling back to wslpath: distribution unknown; use --distro or share WSL_DISTRO_NAME through WSLENV\n")
+	}
+	return runWSLPath(ctx, file, distro)
+}
+
+func translatePath(file string, distro string) (string, error) {
+	t, err := loadTranslator(distro)
+	if err != nil {
+		return "", err
+	}
+	return t.ToWindows(file)
+}
+
+// loadTranslator reads the configuration of the distribution from
+// \\wsl.localhost, or \\wsl$ in older versions of Windows.
+func loadTranslator(distro string) (*wslpath.Translator, error) {
+	for _, uncRoot := range []string{wslpath.UNCRootLocalhost, wslpath.UNCRootLegacy} {
+		root := uncRoot + `\` + distro
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		return wslpath.Load(os.DirFS(root), distro, uncRoot)
+	}
+	return nil, errors.Errorf("distribution not found: %s", distro)
+}
+
+func runWSLPath(ctx context.Context, file string, distro string) (string, error) {
+	args := []string{"wslpath", "-w", file}
+	if distro != "" {
+		args = append([]string{"-d", distro, "--"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "wsl", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "error calling wslpath")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
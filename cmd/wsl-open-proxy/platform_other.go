@@ -0,0 +1,31 @@
+//go:build !windows
+
+package main
+
+import "github.com/pkg/errors"
+
+var errUnsupportedPlatform = errors.New("wsl-open-proxy only runs on Windows")
+
+type unsupportedAssocQuerier struct{}
+
+func newAssocQuerier() assocQuerier {
+	return unsupportedAssocQuerier{}
+}
+
+func (unsupportedAssocQuerier) QueryString(flags int32, str int32, assoc string, extra string) (string, error) {
+	return "", errUnsupportedPlatform
+}
+
+type unsupportedLauncher struct{}
+
+func newLauncher() launcher {
+	return unsupportedLauncher{}
+}
+
+func (unsupportedLauncher) ShellExecute(file string, class string) error {
+	return errUnsupportedPlatform
+}
+
+func (unsupportedLauncher) CreateProcess(cmdline string) error {
+	return errUnsupportedPlatform
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+var modShlwapi = windows.NewLazySystemDLL("Shlwapi.dll")
+var procAssocQueryStringW = modShlwapi.NewProc("AssocQueryStringW")
+var modShell32 = windows.NewLazySystemDLL("Shell32.dll")
+var procShellExecuteExW = modShell32.NewProc("ShellExecuteExW")
+
+const NULL = 0
+
+const (
+	// HRESULT_FROM_WIN32(ERROR_NO_ASSOCIATION)
+	hresultNoAssociation = 0x80070483
+	// HRESULT_FROM_WIN32(ERROR_FILE_NOT_FOUND), returned for unknown classes
+	hresultFileNotFound = 0x80070002
+)
+
+type shlwapiAssocQuerier struct{}
+
+func newAssocQuerier() assocQuerier {
+	return shlwapiAssocQuerier{}
+}
+
+func (shlwapiAssocQuerier) QueryString(flags int32, str int32, assoc string, extra string) (string, error) {
+	return SafeAssocQueryString(flags, str, assoc, extra)
+}
+
+type windowsLauncher struct{}
+
+func newLauncher() launcher {
+	return windowsLauncher{}
+}
+
+func (windowsLauncher) CreateProcess(cmd string) error {
+	commandLinePtr, err := windows.UTF16PtrFromString(cmd)
+	if err != nil {
+		return err
+	}
+	var s windows.StartupInfo
+	var pi windows.ProcessInformation
+	if err := windows.CreateProcess(nil, commandLinePtr, nil, nil, false, 0, nil, nil, &s, &pi); err != nil {
+		return errors.Wrapf(err, "error executing command %#v", cmd)
+	}
+	return nil
+}
+
+func (windowsLauncher) ShellExecute(file string, class string) error {
+	// DelegateExecute handlers are COM objects
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	// S_FALSE means COM is already initialized on the thread, which still
+	// needs to be balanced by CoUninitialize
+	if err := windows.CoInitializeEx(0, windows.COINIT_APARTMENTTHREADED|windows.COINIT_DISABLE_OLE1DDE); err != nil && err != windows.Errno(windows.S_FALSE) {
+		return errors.Wrap(err, "error initializing COM")
+	}
+	defer windows.CoUninitialize()
+
+	verbPtr, err := windows.UTF16PtrFromString("open")
+	if err != nil {
+		return err
+	}
+	filePtr, err := windows.UTF16PtrFromString(file)
+	if err != nil {
+		return errors.Wrap(err, "error converting file to UTF16")
+	}
+	info := SHELLEXECUTEINFOW{
+		FMask:  SEE_MASK_NOASYNC | SEE_MASK_FLAG_NO_UI,
+		LpVerb: verbPtr,
+		LpFile: filePtr,
+		NShow:  windows.SW_SHOWNORMAL,
+	}
+	if class != "" {
+		classPtr, err := windows.UTF16PtrFromString(class)
+		if err != nil {
+			return errors.Wrap(err, "error converting class to UTF16")
+		}
+		info.FMask |= SEE_MASK_CLASSNAME
+		info.LpClass = classPtr
+	}
+	info.CbSize = uint32(unsafe.Sizeof(info))
+	if err := ShellExecuteEx(&info); err != nil {
+		return errors.Wrapf(err, "error opening %#v with ShellExecuteEx", file)
+	}
+	return nil
+}
+
+const (
+	SEE_MASK_DEFAULT        = 0x00000000
+	SEE_MASK_CLASSNAME      = 0x00000001
+	SEE_MASK_CLASSKEY       = 0x00000003
+	SEE_MASK_IDLIST         = 0x00000004
+	SEE_MASK_INVOKEIDLIST   = 0x0000000c
+	SEE_MASK_NOCLOSEPROCESS = 0x00000040
+	SEE_MASK_NOASYNC        = 0x00000100
+	SEE_MASK_FLAG_NO_UI     = 0x00000400
+	SEE_MASK_UNICODE        = 0x00004000
+	SEE_MASK_NO_CONSOLE     = 0x00008000
+	SEE_MASK_NOZONECHECKS   = 0x00800000
+)
+
+type SHELLEXECUTEINFOW struct {
+	CbSize         uint32
+	FMask          uint32
+	Hwnd           windows.Handle
+	LpVerb         *uint16
+	LpFile         *uint16
+	LpParameters   *uint16
+	LpDirectory    *uint16
+	NShow          int32
+	HInstApp       windows.Handle
+	LpIDList       uintptr
+	LpClass        *uint16
+	HkeyClass      windows.Handle
+	DwHotKey       uint32
+	HIconOrMonitor windows.Handle
+	HProcess       windows.Handle
+}
+
+func ShellExecuteEx(pExecInfo *SHELLEXECUTEINFOW) error {
+	r0, _, e1 := procShellExecuteExW.Call(uintptr(unsafe.Pointer(pExecInfo)))
+	if r0 == 0 {
+		return e1
+	}
+	return nil
+}
+
+func SafeAssocQueryString(
+	flags int32,
+	str int32,
+	assoc string,
+	extra string,
+) (string, error) {
+	assocPtr, err := windows.UTF16PtrFromString(assoc)
+	if err != nil {
+		return "", errors.Wrap(err, "error converting assoc to UTF16")
+	}
+	extraPtr, err := windows.UTF16PtrFromString(extra)
+	if err != nil {
+		return "", errors.Wrap(err, "error converting extra to UTF16")
+	}
+	var cch uint32
+	if err := AssocQueryString(
+		flags,
+		str,
+		assocPtr,
+		extraPtr,
+		nil,
+		&cch,
+	); err != nil {
+		return "", errors.Wrap(err, "error pre-calling AssocQueryString")
+	}
+	buf := make([]uint16, cch+1)
+	if err := AssocQueryString(
+		flags,
+		str,
+		assocPtr,
+		extraPtr,
+		&buf[0],
+		&cch,
+	); err != nil {
+		return "", errors.Wrap(err, "error calling AssocQueryString")
+	}
+	return windows.UTF16ToString(buf), nil
+}
+
+func AssocQueryString(
+	flags int32,
+	str int32,
+	pszAssoc *uint16,
+	pszExtra *uint16,
+	pszOut *uint16,
+	pcchOut *uint32,
+) error {
+	r0, _, _ := procAssocQueryStringW.Call(
+		uintptr(flags),
+		uintptr(str),
+		uintptr(unsafe.Pointer(pszAssoc)),
+		uintptr(unsafe.Pointer(pszExtra)),
+		uintptr(unsafe.Pointer(pszOut)),
+		uintptr(unsafe.Pointer(pcchOut)),
+	)
+	// The result is a 32-bit HRESULT; the upper bits of the register are not
+	// guaranteed to be clean. S_FALSE (1) means the size of the string is
+	// returned in pcchOut
+	hr := uint32(r0)
+	if hr == hresultNoAssociation || hr == hresultFileNotFound {
+		return errNoAssociation
+	} else if hr != 0 && hr != 1 {
+		return windows.Errno(hr)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package mimeapps
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+// GenerateCache builds the mimeinfo.cache for the applications directory
+// from the MimeType keys of the desktop entries in it, as
+// update-desktop-database does. Hidden entries are skipped.
+func GenerateCache(appDir string) (*xdgini.Config, error) {
+	desktopIDs := map[string][]string{}
+	err := walkDesktopEntries(appDir, func(id string, filePath string, desktopEntry *xdgini.ConfigGroup) {
+		if hidden, _ := desktopEntry.GetBool("Hidden"); hidden {
+			return
+		}
+		mimeTypes, err := desktopEntry.GetStringList("MimeType")
+		if err != nil {
+			return
+		}
+		for _, mimeType := range mimeTypes {
+			if !slices.Contains(desktopIDs[mimeType], id) {
+				desktopIDs[mimeType] = append(desktopIDs[mimeType], id)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cache := xdgini.ParseConfig("")
+	group := cache.CreateGroup("MIME Cache")
+	for _, mimeType := range slices.Sorted(maps.Keys(desktopIDs)) {
+		ids := desktopIDs[mimeType]
+		slices.Sort(ids)
+		group.CreateEntry(mimeType, xdgini.JoinList(ids, true))
+	}
+	return cache, nil
+}
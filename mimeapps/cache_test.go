@@ -0,0 +1,49 @@
+package mimeapps_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/mimeapps"
+)
+
+func TestGenerateCache(t *testing.T) {
+	testcases := []struct {
+		name   string
+		appDir string
+		output string
+	}{
+		{
+			name:   "system",
+			appDir: "testdata/usr/share/applications",
+			output: "[MIME Cache]\n" +
+				"application/pdf=evince.desktop;\n" +
+				"application/x-pdf=evince.desktop;\n" +
+				"image/gif=gimp.desktop;\n" +
+				"image/png=eog.desktop;gimp.desktop;\n",
+		},
+		{
+			name:   "subdirectory and hidden entry",
+			appDir: "testdata/local/share/applications",
+			output: "[MIME Cache]\n" +
+				"application/pdf=kde-okular.desktop;\n" +
+				"image/png=kde-okular.desktop;\n",
+		},
+		{
+			name:   "missing directory",
+			appDir: "testdata/nonexistent/applications",
+			output: "[MIME Cache]\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cache, err := mimeapps.GenerateCache(tc.appDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.output, cache.String()); diff != "" {
+				t.Errorf("GenerateCache(%q) mismatch (-want +got):\n%s", tc.appDir, diff)
+			}
+		})
+	}
+}
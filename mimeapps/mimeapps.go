@@ -0,0 +1,340 @@
+// Package mimeapps resolves the applications associated with MIME types, as
+// described in the Association between MIME types and applications
+// specification: mimeapps.list files (including desktop-specific ones),
+// mimeinfo.cache and the installed desktop entries.
+package mimeapps
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+// Dirs lists the directories to look up.
+type Dirs struct {
+	ConfigHome string
+	ConfigDirs []string
+	DataHome   string
+	DataDirs   []string
+	// Lowercased desktop names from $XDG_CURRENT_DESKTOP
+	Desktops []string
+}
+
+// DefaultDirs returns the directories from the XDG environment variables.
+func DefaultDirs() Dirs {
+	var desktops []string
+	for _, desktop := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		if desktop != "" {
+			desktops = append(desktops, strings.ToLower(desktop))
+		}
+	}
+	return Dirs{
+		ConfigHome: xdg.ConfigHome,
+		ConfigDirs: xdg.ConfigDirs,
+		DataHome:   xdg.DataHome,
+		DataDirs:   xdg.DataDirs,
+		Desktops:   desktops,
+	}
+}
+
+// appDirs lists the applications directories in the order of precedence.
+func (d Dirs) appDirs() []string {
+	appDirs := []string{path.Join(d.DataHome, "applications")}
+	for _, dataDir := range d.DataDirs {
+		appDirs = append(appDirs, path.Join(dataDir, "applications"))
+	}
+	return appDirs
+}
+
+// MimeAppsListPaths lists the mimeapps.list files in the order of precedence.
+// The files in data directories are deprecated, but still looked up.
+func (d Dirs) MimeAppsListPaths() []string {
+	var dirs []string
+	dirs = append(dirs, d.ConfigHome)
+	dirs = append(dirs, d.ConfigDirs...)
+	dirs = append(dirs, d.appDirs()...)
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, desktop := range d.Desktops {
+			paths = append(paths, path.Join(dir, desktop+"-mimeapps.list"))
+		}
+		paths = append(paths, path.Join(dir, "mimeapps.list"))
+	}
+	return paths
+}
+
+// source is a mimeapps.list or a mimeinfo.cache.
+type source struct {
+	path     string
+	defaults map[string][]string
+	added    map[string][]string
+	removed  map[string][]string
+}
+
+// Database holds the associations read from all the directories.
+type Database struct {
+	// Sources in the order of precedence
+	sources []*source
+	// Maps installed desktop file IDs to the paths of the desktop entries
+	desktopEntries map[string]string
+	mimeDB         *sharedmime.Database
+}
+
+// Association is the result of a lookup.
+type Association struct {
+	DesktopID string
+	// Path to the desktop entry
+	DesktopPath string
+	// The MIME type the association is found for; it may be a parent of the
+	// requested one.
+	MimeType string
+	// The mimeapps.list or mimeinfo.cache where the association is found
+	Source string
+	// Whether the association comes from "Default Applications"
+	Default bool
+}
+
+// Load reads the mimeapps.list files, mimeinfo.cache files and desktop
+// entries. The shared-mime-info database is used for the subclass fallback;
+// it may be nil.
+func Load(dirs Dirs, mimeDB *sharedmime.Database) (*Database, error) {
+	db := &Database{
+		desktopEntries: map[string]string{},
+		mimeDB:         mimeDB,
+	}
+
+	mimeAppsListPaths := dirs.MimeAppsListPaths()
+	appDirs := dirs.appDirs()
+	for _, mimeAppsListPath := range mimeAppsListPaths {
+		config, err := readConfig(mimeAppsListPath)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			db.sources = append(db.sources, &source{
+				path:     mimeAppsListPath,
+				defaults: listValues(config, "Default Applications"),
+				added:    listValues(config, "Added Associations"),
+				removed:  listValues(config, "Removed Associations"),
+			})
+		}
+		// mimeinfo.cache comes after the mimeapps.list in the same directory
+		dir := path.Dir(mimeAppsListPath)
+		if path.Base(mimeAppsListPath) == "mimeapps.list" && slices.Contains(appDirs, dir) {
+			cachePath := path.Join(dir, "mimeinfo.cache")
+			cache, err := readConfig(cachePath)
+			if err != nil {
+				return nil, err
+			}
+			if cache != nil {
+				db.sources = append(db.sources, &source{
+					path:  cachePath,
+					added: listValues(cache, "MIME Cache"),
+				})
+			}
+		}
+	}
+
+	for _, appDir := range appDirs {
+		if err := db.loadDesktopEntries(appDir); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// loadDesktopEntries finds the desktop entries in the applications
+// directory. Entries already found in more important directories win.
+func (db *Database) loadDesktopEntries(appDir string) error {
+	return walkDesktopEntries(appDir, func(id string, filePath string, desktopEntry *xdgini.ConfigGroup) {
+		if _, ok := db.desktopEntries[id]; ok {
+			return
+		}
+		if hidden, _ := desktopEntry.GetBool("Hidden"); hidden {
+			// Hidden entries are regarded as deleted, shadowing the ones in
+			// less important directories
+			db.desktopEntries[id] = ""
+			return
+		}
+		db.desktopEntries[id] = filePath
+	})
+}
+
+// walkDesktopEntries calls f for each desktop entry in the applications
+// directory, including subdirectories. A missing directory is not an error.
+func walkDesktopEntries(appDir string, f func(id string, filePath string, desktopEntry *xdgini.ConfigGroup)) error {
+	err := filepath.WalkDir(appDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(filePath, ".desktop") {
+			return nil
+		}
+		id, ok := DesktopID(appDir, filePath)
+		if !ok {
+			return nil
+		}
+		config, err := readConfig(filePath)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			return nil
+		}
+		desktopEntry, ok := config.Groups["Desktop Entry"]
+		if !ok {
+			desktopEntry = config.CreateGroup("Desktop Entry")
+		}
+		f(id, filePath, desktopEntry)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read desktop entries in %s", appDir)
+	}
+	return nil
+}
+
+// DesktopID returns the desktop file ID of the desktop entry in the
+// applications directory: the relative path with slashes replaced by dashes.
+func DesktopID(appDir string, filePath string) (string, bool) {
+	rel, err := filepath.Rel(appDir, filePath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", "-"), true
+}
+
+// DesktopEntryPath returns the path to the installed desktop entry.
+func (db *Database) DesktopEntryPath(desktopID string) (string, bool) {
+	filePath := db.desktopEntries[desktopID]
+	return filePath, filePath != ""
+}
+
+// DefaultApplication returns the application that xdg-open and the like
+// would use for the MIME type.
+//
+// The first installed application in "Default Applications" is used, looking
+// at the parent types if none is found for the type itself. Otherwise the
+// most preferred associated application is used in the same manner.
+func (db *Database) DefaultApplication(mimeType string) (Association, bool) {
+	mimeTypes := db.withAncestors(mimeType)
+	for _, mimeType := range mimeTypes {
+		for _, src := range db.sources {
+			for _, id := range src.defaults[mimeType] {
+				if desktopPath, ok := db.DesktopEntryPath(id); ok {
+					return Association{
+						DesktopID:   id,
+						DesktopPath: desktopPath,
+						MimeType:    mimeType,
+						Source:      src.path,
+						Default:     true,
+					}, true
+				}
+			}
+		}
+	}
+	for _, mimeType := range mimeTypes {
+		if associations := db.associations(mimeType); len(associations) > 0 {
+			return associations[0], true
+		}
+	}
+	return Association{}, false
+}
+
+// DefaultApplicationForScheme returns the application for the URL scheme,
+// like "https".
+func (db *Database) DefaultApplicationForScheme(scheme string) (Association, bool) {
+	return db.DefaultApplication("x-scheme-handler/" + strings.ToLower(scheme))
+}
+
+// Associations lists the applications associated with the MIME type in the
+// order of preference, as shown in "Open With" menus. The parent types are
+// not looked at.
+func (db *Database) Associations(mimeType string) []Association {
+	return db.associations(mimeType)
+}
+
+func (db *Database) associations(mimeType string) []Association {
+	var associations []Association
+	removed := map[string]bool{}
+	for _, src := range db.sources {
+		for _, id := range src.added[mimeType] {
+			if removed[id] || slices.ContainsFunc(associations, func(a Association) bool {
+				return a.DesktopID == id
+			}) {
+				continue
+			}
+			desktopPath, ok := db.DesktopEntryPath(id)
+			if !ok {
+				continue
+			}
+			associations = append(associations, Association{
+				DesktopID:   id,
+				DesktopPath: desktopPath,
+				MimeType:    mimeType,
+				Source:      src.path,
+			})
+		}
+		// Removals apply to the files with lower precedence
+		for _, id := range src.removed[mimeType] {
+			removed[id] = true
+		}
+	}
+	return associations
+}
+
+// withAncestors lists the MIME type followed by its ancestors, nearest
+// first. text/* types are subclasses of text/plain.
+func (db *Database) withAncestors(mimeType string) []string {
+	mimeTypes := []string{mimeType}
+	for i := 0; i < len(mimeTypes); i++ {
+		var parents []string
+		if db.mimeDB != nil {
+			parents = db.mimeDB.Parents[db.mimeDB.Canonical(mimeTypes[i])]
+		}
+		if strings.HasPrefix(mimeTypes[i], "text/") && mimeTypes[i] != "text/plain" {
+			parents = append(slices.Clone(parents), "text/plain")
+		}
+		for _, parent := range parents {
+			if !slices.Contains(mimeTypes, parent) {
+				mimeTypes = append(mimeTypes, parent)
+			}
+		}
+	}
+	return mimeTypes
+}
+
+// readConfig parses the file; nil is returned if it does not exist.
+func readConfig(filePath string) (*xdgini.Config, error) {
+	text, err := os.ReadFile(filePath)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	return xdgini.ParseConfig(string(text)), nil
+}
+
+func listValues(config *xdgini.Config, groupName string) map[string][]string {
+	values := map[string][]string{}
+	group, ok := config.Groups[groupName]
+	if !ok {
+		return values
+	}
+	for key := range group.Entries {
+		values[key], _ = group.GetStringList(key)
+	}
+	return values
+}
@@ -0,0 +1,194 @@
+package mimeapps_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/mimeapps"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+)
+
+func testDirs() mimeapps.Dirs {
+	return mimeapps.Dirs{
+		ConfigHome: "testdata/config",
+		ConfigDirs: []string{"testdata/etc/xdg"},
+		DataHome:   "testdata/local/share",
+		DataDirs:   []string{"testdata/nonexistent", "testdata/usr/share"},
+		Desktops:   []string{"ubuntu", "gnome"},
+	}
+}
+
+func TestDefaultApplication(t *testing.T) {
+	mimeDB, err := sharedmime.Load([]string{"testdata/usr/share/mime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := mimeapps.Load(testDirs(), mimeDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name        string
+		mimeType    string
+		association *mimeapps.Association
+	}{
+		{
+			name:     "desktop-specific list, skipping missing applications",
+			mimeType: "application/pdf",
+			association: &mimeapps.Association{
+				DesktopID:   "evince.desktop",
+				DesktopPath: "testdata/usr/share/applications/evince.desktop",
+				MimeType:    "application/pdf",
+				Source:      "testdata/config/gnome-mimeapps.list",
+				Default:     true,
+			},
+		},
+		{
+			name:     "user mimeapps.list",
+			mimeType: "text/html",
+			association: &mimeapps.Association{
+				DesktopID:   "firefox.desktop",
+				DesktopPath: "testdata/local/share/applications/firefox.desktop",
+				MimeType:    "text/html",
+				Source:      "testdata/config/mimeapps.list",
+				Default:     true,
+			},
+		},
+		{
+			name:     "added associations, with desktop ID in a subdirectory",
+			mimeType: "image/png",
+			association: &mimeapps.Association{
+				DesktopID:   "kde-okular.desktop",
+				DesktopPath: "testdata/local/share/applications/kde/okular.desktop",
+				MimeType:    "image/png",
+				Source:      "testdata/config/mimeapps.list",
+			},
+		},
+		{
+			name:     "subclass",
+			mimeType: "image/x-foo",
+			association: &mimeapps.Association{
+				DesktopID:   "kde-okular.desktop",
+				DesktopPath: "testdata/local/share/applications/kde/okular.desktop",
+				MimeType:    "image/png",
+				Source:      "testdata/config/mimeapps.list",
+			},
+		},
+		{
+			name:     "text/plain fallback",
+			mimeType: "text/x-csrc",
+			association: &mimeapps.Association{
+				DesktopID:   "gedit.desktop",
+				DesktopPath: "testdata/usr/share/applications/gedit.desktop",
+				MimeType:    "text/plain",
+				Source:      "testdata/etc/xdg/mimeapps.list",
+				Default:     true,
+			},
+		},
+		{
+			name:     "hidden desktop entry",
+			mimeType: "video/mp4",
+			association: &mimeapps.Association{
+				DesktopID:   "vlc.desktop",
+				DesktopPath: "testdata/usr/share/applications/vlc.desktop",
+				MimeType:    "video/mp4",
+				Source:      "testdata/etc/xdg/mimeapps.list",
+				Default:     true,
+			},
+		},
+		{
+			name:        "unknown",
+			mimeType:    "application/x-unknown",
+			association: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			association, ok := db.DefaultApplication(tc.mimeType)
+			var got *mimeapps.Association
+			if ok {
+				got = &association
+			}
+			if diff := cmp.Diff(tc.association, got); diff != "" {
+				t.Errorf("DefaultApplication(%q) mismatch (-want +got):\n%s", tc.mimeType, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultApplicationForScheme(t *testing.T) {
+	db, err := mimeapps.Load(testDirs(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mimeapps.Association{
+		DesktopID:   "firefox.desktop",
+		DesktopPath: "testdata/local/share/applications/firefox.desktop",
+		MimeType:    "x-scheme-handler/https",
+		Source:      "testdata/usr/share/applications/mimeinfo.cache",
+	}
+	association, ok := db.DefaultApplicationForScheme("HTTPS")
+	if !ok {
+		t.Fatal("DefaultApplicationForScheme() not found")
+	}
+	if diff := cmp.Diff(want, association); diff != "" {
+		t.Errorf("DefaultApplicationForScheme() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssociations(t *testing.T) {
+	db, err := mimeapps.Load(testDirs(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, association := range db.Associations("image/png") {
+		ids = append(ids, association.DesktopID)
+	}
+	// gimp.desktop is removed in the user's mimeapps.list
+	if diff := cmp.Diff([]string{"kde-okular.desktop", "eog.desktop"}, ids); diff != "" {
+		t.Errorf("Associations() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMimeAppsListPaths(t *testing.T) {
+	want := []string{
+		"testdata/config/ubuntu-mimeapps.list",
+		"testdata/config/gnome-mimeapps.list",
+		"testdata/config/mimeapps.list",
+		"testdata/etc/xdg/ubuntu-mimeapps.list",
+		"testdata/etc/xdg/gnome-mimeapps.list",
+		"testdata/etc/xdg/mimeapps.list",
+		"testdata/local/share/applications/ubuntu-mimeapps.list",
+		"testdata/local/share/applications/gnome-mimeapps.list",
+		"testdata/local/share/applications/mimeapps.list",
+		"testdata/nonexistent/applications/ubuntu-mimeapps.list",
+		"testdata/nonexistent/applications/gnome-mimeapps.list",
+		"testdata/nonexistent/applications/mimeapps.list",
+		"testdata/usr/share/applications/ubuntu-mimeapps.list",
+		"testdata/usr/share/applications/gnome-mimeapps.list",
+		"testdata/usr/share/applications/mimeapps.list",
+	}
+	if diff := cmp.Diff(want, testDirs().MimeAppsListPaths()); diff != "" {
+		t.Errorf("MimeAppsListPaths() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDesktopID(t *testing.T) {
+	testcases := []struct {
+		filePath string
+		id       string
+		ok       bool
+	}{
+		{"/usr/share/applications/firefox.desktop", "firefox.desktop", true},
+		{"/usr/share/applications/kde4/okular.desktop", "kde4-okular.desktop", true},
+		{"/usr/share/other/firefox.desktop", "", false},
+	}
+	for _, tc := range testcases {
+		id, ok := mimeapps.DesktopID("/usr/share/applications", tc.filePath)
+		if id != tc.id || ok != tc.ok {
+			t.Errorf("DesktopID(%q) = %q, %v, want %q, %v", tc.filePath, id, ok, tc.id, tc.ok)
+		}
+	}
+}
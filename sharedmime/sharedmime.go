@@ -0,0 +1,255 @@
+// Package sharedmime reads the shared-mime-info database installed under
+// $XDG_DATA_DIRS/mime, as described in the Shared MIME-info Database
+// specification.
+package sharedmime
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/pkg/errors"
+)
+
+type Glob struct {
+	Weight        int
+	MimeType      string
+	Pattern       string
+	CaseSensitive bool
+}
+
+type Database struct {
+	Globs []Glob
+	// Maps an alias to its canonical MIME type
+	Aliases map[string]string
+	// Maps a MIME type to its direct parents (canonical names)
+	Parents map[string][]string
+}
+
+// DefaultDirs lists the mime directories in the order of precedence.
+func DefaultDirs() []string {
+	dirs := []string{path.Join(xdg.DataHome, "mime")}
+	for _, dataDir := range xdg.DataDirs {
+		dirs = append(dirs, path.Join(dataDir, "mime"))
+	}
+	return dirs
+}
+
+// Load reads the database from the mime directories, given in the order of
+// precedence. Missing directories and files are skipped; an error is returned
+// if no glob file is found at all.
+func Load(dirs []string) (*Database, error) {
+	db := &Database{
+		Aliases: map[string]string{},
+		Parents: map[string][]string{},
+	}
+	found := false
+	// Read the least important directory first so that __NOGLOBS__ can
+	// discard the globs defined so far
+	for _, dir := range slices.Backward(dirs) {
+		globsFound, err := db.loadGlobs(dir)
+		if err != nil {
+			return nil, err
+		}
+		found = found || globsFound
+		if err := readLines(path.Join(dir, "aliases"), func(fields []string) {
+			if len(fields) == 2 {
+				db.Aliases[fields[0]] = fields[1]
+			}
+		}); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := readLines(path.Join(dir, "subclasses"), func(fields []string) {
+			if len(fields) == 2 && !slices.Contains(db.Parents[fields[0]], fields[1]) {
+				db.Parents[fields[0]] = append(db.Parents[fields[0]], fields[1])
+			}
+		}); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("shared-mime-info database not found in %s", strings.Join(dirs, ", "))
+	}
+	return db, nil
+}
+
+// loadGlobs reads globs2, or globs if globs2 does not exist.
+func (db *Database) loadGlobs(dir string) (bool, error) {
+	var globs []Glob
+	err := readColonLines(path.Join(dir, "globs2"), func(fields []string) {
+		// weight:mimetype:glob[:flags]
+		if len(fields) < 3 {
+			return
+		}
+		weight, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return
+		}
+		glob := Glob{
+			Weight:   weight,
+			MimeType: fields[1],
+			Pattern:  fields[2],
+		}
+		if len(fields) >= 4 {
+			glob.CaseSensitive = slices.Contains(strings.Split(fields[3], ","), "cs")
+		}
+		globs = append(globs, glob)
+	})
+	if err != nil && os.IsNotExist(err) {
+		// mimetype:glob
+		err = readColonLines(path.Join(dir, "globs"), func(fields []string) {
+			if len(fields) == 2 {
+				globs = append(globs, Glob{
+					Weight:   50,
+					MimeType: fields[0],
+					Pattern:  fields[1],
+				})
+			}
+		})
+	}
+	if err != nil && os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	for _, glob := range globs {
+		if glob.Pattern == "__NOGLOBS__" {
+			db.Globs = slices.DeleteFunc(db.Globs, func(g Glob) bool {
+				return g.MimeType == glob.MimeType
+			})
+		}
+	}
+	for _, glob := range globs {
+		if glob.Pattern != "__NOGLOBS__" {
+			db.Globs = append(db.Globs, glob)
+		}
+	}
+	return true, nil
+}
+
+// Canonical resolves the alias to its canonical MIME type.
+func (db *Database) Canonical(mimeType string) string {
+	if canonical, ok := db.Aliases[mimeType]; ok {
+		return canonical
+	}
+	return mimeType
+}
+
+// AliasesOf lists the aliases of the MIME type in alphabetical order.
+func (db *Database) AliasesOf(mimeType string) []string {
+	canonical := db.Canonical(mimeType)
+	var aliases []string
+	for alias, target := range db.Aliases {
+		if target == canonical {
+			aliases = append(aliases, alias)
+		}
+	}
+	slices.Sort(aliases)
+	return aliases
+}
+
+// MimeTypesForExtension lists the canonical MIME types whose globs match a
+// file with the extension (like ".pdf"), in the descending order of weight.
+// As in the spec, case-sensitive globs take priority over the others.
+func (db *Database) MimeTypesForExtension(extension string) []string {
+	fileName := "file" + extension
+	type match struct {
+		weight   int
+		mimeType string
+	}
+	var matches []match
+	globs := slices.DeleteFunc(slices.Clone(db.Globs), func(glob Glob) bool {
+		return !glob.CaseSensitive || !matchGlob(glob, fileName)
+	})
+	if len(globs) == 0 {
+		globs = slices.DeleteFunc(slices.Clone(db.Globs), func(glob Glob) bool {
+			return glob.CaseSensitive || !matchGlob(glob, fileName)
+		})
+	}
+	for _, glob := range globs {
+		idx := slices.IndexFunc(matches, func(m match) bool {
+			return m.mimeType == glob.MimeType
+		})
+		if idx < 0 {
+			matches = append(matches, match{glob.Weight, glob.MimeType})
+		} else {
+			matches[idx].weight = max(matches[idx].weight, glob.Weight)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.weight != b.weight {
+			return b.weight - a.weight
+		}
+		return strings.Compare(a.mimeType, b.mimeType)
+	})
+	mimeTypes := make([]string, 0, len(matches))
+	for _, m := range matches {
+		mimeTypes = append(mimeTypes, m.mimeType)
+	}
+	return mimeTypes
+}
+
+// ExpandExtension lists the MIME types matching the extension together with
+// their aliases. An empty result means the extension is unknown.
+func (db *Database) ExpandExtension(extension string) []string {
+	var mimeTypes []string
+	for _, mimeType := range db.MimeTypesForExtension(extension) {
+		mimeTypes = append(mimeTypes, mimeType)
+		mimeTypes = append(mimeTypes, db.AliasesOf(mimeType)...)
+	}
+	return mimeTypes
+}
+
+func matchGlob(glob Glob, fileName string) bool {
+	pattern := glob.Pattern
+	if !glob.CaseSensitive {
+		pattern = strings.ToLower(pattern)
+		fileName = strings.ToLower(fileName)
+	}
+	matched, err := path.Match(pattern, fileName)
+	return err == nil && matched
+}
+
+// readLines calls f with the space-separated fields of each non-comment line.
+func readLines(filePath string, f func(fields []string)) error {
+	return scanFile(filePath, func(line string) {
+		f(strings.Fields(line))
+	})
+}
+
+// readColonLines calls f with the colon-separated fields of each non-comment line.
+func readColonLines(filePath string, f func(fields []string)) error {
+	return scanFile(filePath, func(line string) {
+		f(strings.Split(line, ":"))
+	})
+}
+
+// scanFile calls f for each non-comment line. Missing files are reported as
+// errors satisfying os.IsNotExist.
+func scanFile(filePath string, f func(line string)) error {
+	file, err := os.Open(filePath)
+	if err != nil && os.IsNotExist(err) {
+		return err
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to open %s", filePath)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package sharedmime_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/sharedmime"
+)
+
+func TestExpandExtension(t *testing.T) {
+	db, err := sharedmime.Load([]string{"testdata/local/mime", "testdata/nonexistent/mime", "testdata/mime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		extension string
+		mimeTypes []string
+	}{
+		{".pdf", []string{"application/pdf", "application/acrobat", "application/x-pdf"}},
+		{".PDF", []string{"application/pdf", "application/acrobat", "application/x-pdf"}},
+		{".bmp", []string{"image/bmp", "image/x-bmp", "image/x-ms-bmp"}},
+		{".ogg", []string{"audio/ogg", "audio/x-vorbis+ogg", "video/ogg"}},
+		{".c", []string{"text/x-csrc"}},
+		{".C", []string{"text/x-c++src"}},
+		// Removed by __NOGLOBS__
+		{".md", []string{"text/markdown"}},
+		{".mine", []string{"text/x-mylang"}},
+		{".unknown", nil},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.extension, func(t *testing.T) {
+			if diff := cmp.Diff(tc.mimeTypes, db.ExpandExtension(tc.extension)); diff != "" {
+				t.Errorf("ExpandExtension(%q) mismatch (-want +got):\n%s", tc.extension, diff)
+			}
+		})
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	if _, err := sharedmime.Load([]string{"testdata/nonexistent/mime"}); err == nil {
+		t.Error("Load() succeeded, want error")
+	}
+}
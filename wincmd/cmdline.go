@@ -0,0 +1,117 @@
+package wincmd
+
+import "strings"
+
+// EscapeArg quotes the argument so that CommandLineToArgvW and the C runtime
+// parse it back as a single argument.
+func EscapeArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+	return `"` + escapeQuoted(arg, true) + `"`
+}
+
+// escapeQuoted escapes the text to be placed between double quotes: quotes
+// are escaped with backslashes, and backslashes are doubled where they
+// precede a quote. Trailing backslashes are doubled if closingQuote is set,
+// since a quote follows them.
+func escapeQuoted(s string, closingQuote bool) string {
+	var buf strings.Builder
+	numBackslashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			numBackslashes++
+		case '"':
+			buf.WriteString(strings.Repeat(`\`, numBackslashes+1))
+			numBackslashes = 0
+		default:
+			numBackslashes = 0
+		}
+		buf.WriteByte(s[i])
+	}
+	if closingQuote {
+		buf.WriteString(strings.Repeat(`\`, numBackslashes))
+	}
+	return buf.String()
+}
+
+// JoinCommandLine quotes each argument with EscapeArg and joins them with
+// spaces.
+func JoinCommandLine(args []string) string {
+	escaped := make([]string, 0, len(args))
+	for _, arg := range args {
+		escaped = append(escaped, EscapeArg(arg))
+	}
+	return strings.Join(escaped, " ")
+}
+
+// SplitCommandLine splits the command line into arguments like
+// CommandLineToArgvW. The program name, the first argument, ends at the first
+// whitespace or, if it starts with a quote, at the next quote; backslashes
+// in it are not special.
+func SplitCommandLine(cmdline string) []string {
+	var args []string
+	cmdline = strings.TrimLeft(cmdline, " \t")
+	if cmdline == "" {
+		return nil
+	}
+
+	var program string
+	if cmdline[0] == '"' {
+		end := strings.IndexByte(cmdline[1:], '"')
+		if end < 0 {
+			program, cmdline = cmdline[1:], ""
+		} else {
+			program, cmdline = cmdline[1:end+1], cmdline[end+2:]
+		}
+	} else {
+		end := strings.IndexAny(cmdline, " \t")
+		if end < 0 {
+			end = len(cmdline)
+		}
+		program, cmdline = cmdline[:end], cmdline[end:]
+	}
+	args = append(args, program)
+
+	for {
+		cmdline = strings.TrimLeft(cmdline, " \t")
+		if cmdline == "" {
+			return args
+		}
+		var arg strings.Builder
+		inQuotes := false
+		numBackslashes := 0
+		i := 0
+	argLoop:
+		for ; i < len(cmdline); i++ {
+			c := cmdline[i]
+			switch {
+			case c == '\\':
+				numBackslashes++
+				continue
+			case c == '"':
+				arg.WriteString(strings.Repeat(`\`, numBackslashes/2))
+				if numBackslashes%2 == 1 {
+					arg.WriteByte('"')
+				} else if inQuotes && i+1 < len(cmdline) && cmdline[i+1] == '"' {
+					// "" in quotes is a literal quote
+					arg.WriteByte('"')
+					i++
+				} else {
+					inQuotes = !inQuotes
+				}
+				numBackslashes = 0
+				continue
+			case (c == ' ' || c == '\t') && !inQuotes:
+				break argLoop
+			}
+			arg.WriteString(strings.Repeat(`\`, numBackslashes))
+			numBackslashes = 0
+			arg.WriteByte(c)
+		}
+		arg.WriteString(strings.Repeat(`\`, numBackslashes))
+		args = append(args, arg.String())
+		cmdline = cmdline[i:]
+	}
+}
@@ -0,0 +1,148 @@
+// Package wincmd expands the command templates of Windows file and URL
+// associations, like `"C:\Program Files\App\app.exe" /n "%1"`, into command
+// lines for CreateProcess, following the rules of the Windows shell. It does
+// not depend on Windows APIs so that it can be tested on any platform.
+package wincmd
+
+import (
+	"os"
+	"strings"
+)
+
+// Expand expands the placeholders and environment variables in the command
+// template, as returned by ASSOCSTR_COMMAND or found under shell\open\command
+// in the registry.
+//
+// args[0] is the file or URL to open and args[1:] are additional parameters.
+// The following placeholders are supported:
+//
+//   - %1, %0, %L, %D and %V: the file
+//   - %2 to %9: the corresponding parameter, or nothing if missing
+//   - %* and %~: all parameters
+//   - %W: the directory of the file
+//   - %S: the show command, always 1 (SW_SHOWNORMAL)
+//   - %H: the hotkey, always 0
+//   - %I: the item ID list, which is not available and expands to nothing
+//
+// Values are quoted as needed: inside a quoted part of the template only
+// quotes and backslashes are escaped, and elsewhere each value is quoted as
+// a separate argument. If the template has no placeholder for the file, the
+// file is appended as an argument, as is done by the shell for bare
+// executable paths; the same holds for the parameters.
+//
+// Environment variables like %ProgramFiles% are expanded as in
+// ExpandEnvironmentStrings, leaving unknown ones as they are. lookupEnv looks
+// up the variables; nil means os.LookupEnv.
+func Expand(template string, args []string, lookupEnv func(string) (string, bool)) string {
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	var file string
+	var params []string
+	if len(args) > 0 {
+		file, params = args[0], args[1:]
+	}
+
+	var buf strings.Builder
+	inQuotes := false
+	fileUsed := false
+	paramsUsed := false
+	// insert writes a single value at the current position of the template
+	insert := func(value string, next string) {
+		if inQuotes {
+			buf.WriteString(escapeQuoted(value, strings.HasPrefix(next, `"`)))
+		} else if value != "" {
+			buf.WriteString(EscapeArg(value))
+		}
+	}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c == '"' {
+			inQuotes = !inQuotes
+			buf.WriteByte(c)
+			continue
+		} else if c != '%' || i+1 >= len(template) {
+			buf.WriteByte(c)
+			continue
+		}
+
+		if value, length, ok := expandEnv(template[i:], lookupEnv); ok {
+			buf.WriteString(value)
+			i += length - 1
+			continue
+		}
+
+		next := template[i+2:]
+		switch p := template[i+1]; {
+		case strings.IndexByte("01LlDdVv", p) >= 0:
+			insert(file, next)
+			fileUsed = true
+		case p >= '2' && p <= '9':
+			n := int(p - '2')
+			if n < len(params) {
+				insert(params[n], next)
+			}
+			paramsUsed = true
+		case p == '*' || p == '~':
+			if inQuotes {
+				buf.WriteString(escapeQuoted(strings.Join(params, " "), strings.HasPrefix(next, `"`)))
+			} else {
+				buf.WriteString(JoinCommandLine(params))
+			}
+			paramsUsed = true
+		case p == 'W' || p == 'w':
+			insert(windowsDir(file), next)
+		case p == 'S' || p == 's':
+			buf.WriteString("1")
+		case p == 'H' || p == 'h':
+			buf.WriteString("0")
+		case p == 'I' || p == 'i':
+			// No item ID list
+		default:
+			buf.WriteString(template[i : i+2])
+		}
+		i++
+	}
+
+	cmdline := buf.String()
+	if !fileUsed && len(args) > 0 {
+		cmdline = strings.TrimRight(cmdline, " \t") + " " + EscapeArg(file)
+	}
+	if !paramsUsed && len(params) > 0 {
+		cmdline = strings.TrimRight(cmdline, " \t") + " " + JoinCommandLine(params)
+	}
+	return cmdline
+}
+
+// expandEnv expands an environment variable reference like %ProgramFiles%
+// at the beginning of s, returning the value and the length of the
+// reference.
+func expandEnv(s string, lookupEnv func(string) (string, bool)) (string, int, bool) {
+	end := strings.IndexByte(s[1:], '%')
+	if end <= 0 {
+		return "", 0, false
+	}
+	name := s[1 : end+1]
+	if strings.ContainsAny(name, " \t\"") {
+		return "", 0, false
+	}
+	value, ok := lookupEnv(name)
+	if !ok {
+		return "", 0, false
+	}
+	return value, end + 2, true
+}
+
+// windowsDir returns the directory part of a Windows path.
+func windowsDir(path string) string {
+	i := strings.LastIndexAny(path, `\/`)
+	if i < 0 {
+		return ""
+	}
+	dir := path[:i]
+	if strings.HasSuffix(dir, ":") {
+		// Keep the root of the drive, like C:\
+		dir = path[:i+1]
+	}
+	return dir
+}
@@ -0,0 +1,326 @@
+package wincmd_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/wincmd"
+)
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{
+		"ProgramFiles":      `C:\Program Files`,
+		"ProgramFiles(x86)": `C:\Program Files (x86)`,
+		"SystemRoot":        `C:\Windows`,
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	testcases := []struct {
+		name     string
+		template string
+		args     []string
+		cmdline  string
+		argv     []string
+	}{
+		{
+			name:     "quoted placeholder",
+			template: `"C:\Program Files\Adobe\Acrobat.exe" /n "%1"`,
+			args:     []string{`C:\Users\me\a b.pdf`},
+			cmdline:  `"C:\Program Files\Adobe\Acrobat.exe" /n "C:\Users\me\a b.pdf"`,
+			argv:     []string{`C:\Program Files\Adobe\Acrobat.exe`, "/n", `C:\Users\me\a b.pdf`},
+		},
+		{
+			name:     "unquoted placeholder",
+			template: `C:\Windows\notepad.exe %1`,
+			args:     []string{`C:\Users\me\a b.txt`},
+			cmdline:  `C:\Windows\notepad.exe "C:\Users\me\a b.txt"`,
+			argv:     []string{`C:\Windows\notepad.exe`, `C:\Users\me\a b.txt`},
+		},
+		{
+			name:     "unquoted placeholder without spaces",
+			template: `C:\Windows\notepad.exe %1`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `C:\Windows\notepad.exe C:\a.txt`,
+			argv:     []string{`C:\Windows\notepad.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "long name",
+			template: `"C:\app.exe" "%L"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" "C:\a.txt"`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "lowercase long name",
+			template: `"C:\app.exe" %l`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" C:\a.txt`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "file as %0",
+			template: `C:\app.exe %0`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `C:\app.exe C:\a.txt`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "%D and %V",
+			template: `C:\app.exe /d %D /v %V`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `C:\app.exe /d C:\a.txt /v C:\a.txt`,
+			argv:     []string{`C:\app.exe`, "/d", `C:\a.txt`, "/v", `C:\a.txt`},
+		},
+		{
+			name:     "bare executable",
+			template: `C:\Windows\notepad.exe`,
+			args:     []string{`C:\Users\me\a b.txt`},
+			cmdline:  `C:\Windows\notepad.exe "C:\Users\me\a b.txt"`,
+			argv:     []string{`C:\Windows\notepad.exe`, `C:\Users\me\a b.txt`},
+		},
+		{
+			name:     "bare quoted executable with trailing space",
+			template: `"C:\Program Files\App\app.exe" `,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\Program Files\App\app.exe" C:\a.txt`,
+			argv:     []string{`C:\Program Files\App\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "bare executable with parameters",
+			template: `C:\app.exe`,
+			args:     []string{`C:\a.txt`, "--foo", "b c"},
+			cmdline:  `C:\app.exe C:\a.txt --foo "b c"`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, "--foo", "b c"},
+		},
+		{
+			name:     "all parameters",
+			template: `"C:\app.exe" "%1" %*`,
+			args:     []string{`C:\a.txt`, "--foo", "b c"},
+			cmdline:  `"C:\app.exe" "C:\a.txt" --foo "b c"`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, "--foo", "b c"},
+		},
+		{
+			name:     "all parameters without parameters",
+			template: `"C:\app.exe" "%1" %*`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" "C:\a.txt" `,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "all parameters with tilde",
+			template: `"C:\app.exe" %1 %~`,
+			args:     []string{`C:\a.txt`, "x"},
+			cmdline:  `"C:\app.exe" C:\a.txt x`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, "x"},
+		},
+		{
+			name:     "only all parameters",
+			template: `"C:\app.exe" %*`,
+			args:     []string{`C:\a b.txt`, "x"},
+			cmdline:  `"C:\app.exe" x "C:\a b.txt"`,
+			argv:     []string{`C:\app.exe`, "x", `C:\a b.txt`},
+		},
+		{
+			name:     "numbered parameters",
+			template: `"C:\app.exe" "%1" /a %2 /b "%3" /c %9`,
+			args:     []string{`C:\a.txt`, "x y", "z"},
+			cmdline:  `"C:\app.exe" "C:\a.txt" /a "x y" /b "z" /c `,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, "/a", "x y", "/b", "z", "/c"},
+		},
+		{
+			name:     "missing quoted parameter",
+			template: `"C:\app.exe" "%1" "%2"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" "C:\a.txt" ""`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, ""},
+		},
+		{
+			name:     "rundll32",
+			template: `%SystemRoot%\System32\rundll32.exe "%ProgramFiles%\Windows Photo Viewer\PhotoViewer.dll", ImageView_Fullscreen %1`,
+			args:     []string{`C:\Users\me\My Pictures\a.png`},
+			cmdline:  `C:\Windows\System32\rundll32.exe "C:\Program Files\Windows Photo Viewer\PhotoViewer.dll", ImageView_Fullscreen "C:\Users\me\My Pictures\a.png"`,
+			argv:     []string{`C:\Windows\System32\rundll32.exe`, `C:\Program Files\Windows Photo Viewer\PhotoViewer.dll,`, "ImageView_Fullscreen", `C:\Users\me\My Pictures\a.png`},
+		},
+		{
+			name:     "environment variable with parentheses",
+			template: `"%ProgramFiles(x86)%\App\app.exe" "%1"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\Program Files (x86)\App\app.exe" "C:\a.txt"`,
+			argv:     []string{`C:\Program Files (x86)\App\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "unknown environment variable",
+			template: `"%NoSuchVar%\app.exe" "%1"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"%NoSuchVar%\app.exe" "C:\a.txt"`,
+			argv:     []string{`%NoSuchVar%\app.exe`, `C:\a.txt`},
+		},
+		{
+			name:     "environment variable is not confused with placeholders",
+			template: `"C:\app.exe" "%1" %SystemRoot%`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" "C:\a.txt" C:\Windows`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, `C:\Windows`},
+		},
+		{
+			name:     "directory, show command and hotkey",
+			template: `"C:\app.exe" /dir "%W" /show %S /hotkey %H "%1"`,
+			args:     []string{`C:\Users\me\a.txt`},
+			cmdline:  `"C:\app.exe" /dir "C:\Users\me" /show 1 /hotkey 0 "C:\Users\me\a.txt"`,
+			argv:     []string{`C:\app.exe`, "/dir", `C:\Users\me`, "/show", "1", "/hotkey", "0", `C:\Users\me\a.txt`},
+		},
+		{
+			name:     "directory at the root",
+			template: `"C:\app.exe" /dir %W "%1"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" /dir C:\ "C:\a.txt"`,
+			argv:     []string{`C:\app.exe`, "/dir", `C:\`, `C:\a.txt`},
+		},
+		{
+			name:     "item ID list",
+			template: `"C:\app.exe" /idlist,%I,%L`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" /idlist,,C:\a.txt`,
+			argv:     []string{`C:\app.exe`, `/idlist,,C:\a.txt`},
+		},
+		{
+			name:     "unknown placeholder",
+			template: `"C:\app.exe" %Z "%1" 100%`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" %Z "C:\a.txt" 100%`,
+			argv:     []string{`C:\app.exe`, "%Z", `C:\a.txt`, "100%"},
+		},
+		{
+			name:     "URL",
+			template: `"C:\Program Files\Mozilla Firefox\firefox.exe" -osint -url "%1"`,
+			args:     []string{"https://example.com/?a=1&b=2"},
+			cmdline:  `"C:\Program Files\Mozilla Firefox\firefox.exe" -osint -url "https://example.com/?a=1&b=2"`,
+			argv:     []string{`C:\Program Files\Mozilla Firefox\firefox.exe`, "-osint", "-url", "https://example.com/?a=1&b=2"},
+		},
+		{
+			name:     "quote in unquoted value",
+			template: `C:\app.exe %1`,
+			args:     []string{`https://example.com/"a b"`},
+			cmdline:  `C:\app.exe "https://example.com/\"a b\""`,
+			argv:     []string{`C:\app.exe`, `https://example.com/"a b"`},
+		},
+		{
+			name:     "quote in quoted value",
+			template: `C:\app.exe "%1"`,
+			args:     []string{`https://example.com/"a"`},
+			cmdline:  `C:\app.exe "https://example.com/\"a\""`,
+			argv:     []string{`C:\app.exe`, `https://example.com/"a"`},
+		},
+		{
+			name:     "trailing backslash in quoted value",
+			template: `C:\app.exe "%1" /x`,
+			args:     []string{`C:\Users\me\dir\`},
+			cmdline:  `C:\app.exe "C:\Users\me\dir\\" /x`,
+			argv:     []string{`C:\app.exe`, `C:\Users\me\dir\`, "/x"},
+		},
+		{
+			name:     "trailing backslash in unquoted value",
+			template: `C:\app.exe %1 /x`,
+			args:     []string{`C:\My Files\`},
+			cmdline:  `C:\app.exe "C:\My Files\\" /x`,
+			argv:     []string{`C:\app.exe`, `C:\My Files\`, "/x"},
+		},
+		{
+			name:     "UNC path to WSL",
+			template: `"C:\app.exe" "%1"`,
+			args:     []string{`\\wsl.localhost\Ubuntu\home\me\a b.txt`},
+			cmdline:  `"C:\app.exe" "\\wsl.localhost\Ubuntu\home\me\a b.txt"`,
+			argv:     []string{`C:\app.exe`, `\\wsl.localhost\Ubuntu\home\me\a b.txt`},
+		},
+		{
+			name:     "placeholder in the middle of a word",
+			template: `C:\app.exe /file=%1`,
+			args:     []string{`C:\a b.txt`},
+			cmdline:  `C:\app.exe /file="C:\a b.txt"`,
+			argv:     []string{`C:\app.exe`, `/file=C:\a b.txt`},
+		},
+		{
+			name:     "file used twice",
+			template: `"C:\app.exe" "%1" "%1"`,
+			args:     []string{`C:\a.txt`},
+			cmdline:  `"C:\app.exe" "C:\a.txt" "C:\a.txt"`,
+			argv:     []string{`C:\app.exe`, `C:\a.txt`, `C:\a.txt`},
+		},
+		{
+			name:     "no arguments",
+			template: `"C:\app.exe" "%1"`,
+			args:     nil,
+			cmdline:  `"C:\app.exe" ""`,
+			argv:     []string{`C:\app.exe`, ""},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmdline := wincmd.Expand(tc.template, tc.args, lookupEnv)
+			if cmdline != tc.cmdline {
+				t.Errorf("Expand() = %s, want %s", cmdline, tc.cmdline)
+			}
+			if diff := cmp.Diff(tc.argv, wincmd.SplitCommandLine(cmdline)); diff != "" {
+				t.Errorf("SplitCommandLine(Expand()) mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEscapeArg(t *testing.T) {
+	testcases := []struct {
+		arg     string
+		escaped string
+	}{
+		{"foo", "foo"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{"a\tb", "\"a\tb\""},
+		{`C:\a\b`, `C:\a\b`},
+		{`C:\a b\`, `"C:\a b\\"`},
+		{`a"b`, `"a\"b"`},
+		{`a\"b`, `"a\\\"b"`},
+		{`a\\b c`, `"a\\b c"`},
+	}
+	for _, tc := range testcases {
+		if got := wincmd.EscapeArg(tc.arg); got != tc.escaped {
+			t.Errorf("EscapeArg(%q) = %s, want %s", tc.arg, got, tc.escaped)
+		}
+		argv := wincmd.SplitCommandLine("app.exe " + wincmd.EscapeArg(tc.arg))
+		if diff := cmp.Diff([]string{"app.exe", tc.arg}, argv); diff != "" {
+			t.Errorf("SplitCommandLine(EscapeArg(%q)) mismatch (-want +got):\n%s", tc.arg, diff)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	testcases := []struct {
+		cmdline string
+		argv    []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"app.exe", []string{"app.exe"}},
+		{`"C:\Program Files\app.exe"`, []string{`C:\Program Files\app.exe`}},
+		{`"C:\Program Files\app.exe"a b`, []string{`C:\Program Files\app.exe`, "a", "b"}},
+		{`C:\dir\app.exe a\\b`, []string{`C:\dir\app.exe`, `a\\b`}},
+		{`app.exe "a b" c`, []string{"app.exe", "a b", "c"}},
+		{`app.exe a"b c"d`, []string{"app.exe", "ab cd"}},
+		{`app.exe \"a`, []string{"app.exe", `"a`}},
+		{`app.exe \\"a b"`, []string{"app.exe", `\a b`}},
+		{`app.exe \\\"a`, []string{"app.exe", `\"a`}},
+		{`app.exe "a""b"`, []string{"app.exe", `a"b`}},
+		{`app.exe "a`, []string{"app.exe", "a"}},
+		{"app.exe\ta  \t b", []string{"app.exe", "a", "b"}},
+		{`app.exe ""`, []string{"app.exe", ""}},
+	}
+	for _, tc := range testcases {
+		if diff := cmp.Diff(tc.argv, wincmd.SplitCommandLine(tc.cmdline)); diff != "" {
+			t.Errorf("SplitCommandLine(%s) mismatch (-want +got):\n%s", tc.cmdline, diff)
+		}
+	}
+}
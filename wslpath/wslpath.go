@@ -0,0 +1,230 @@
+// Package wslpath translates Linux paths in a WSL distribution to Windows
+// paths without running wslpath(1): paths under the mount points of Windows
+// drives become drive paths like C:\Users, and other paths become UNC paths
+// like \\wsl.localhost\Ubuntu\home.
+package wslpath
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+// ErrUntranslatable is returned for paths that the translator cannot handle,
+// like relative paths and names with characters not allowed in Windows. The
+// caller may fall back to wslpath(1) for them.
+var ErrUntranslatable = errors.New("path cannot be translated without wslpath")
+
+const (
+	// UNC root of WSL distributions in recent versions of Windows
+	UNCRootLocalhost = `\\wsl.localhost`
+	// UNC root of WSL distributions in older versions of Windows
+	UNCRootLegacy = `\\wsl$`
+	// Default of automount.root in wsl.conf
+	DefaultAutomountRoot = "/mnt/"
+)
+
+// Mount is a Windows drive or share mounted in the distribution.
+type Mount struct {
+	// Windows path of the mounted directory, like C:\
+	Source string
+	// Linux mount point, like /mnt/c
+	Target string
+}
+
+// Translator translates paths in a distribution to Windows paths.
+type Translator struct {
+	// Name of the distribution, like Ubuntu
+	Distro string
+	// UNC root of the distributions, like UNCRootLocalhost
+	UNCRoot string
+	// Where Windows drives are mounted, like /mnt/
+	AutomountRoot string
+	// Mounted Windows drives; if nil, the drives are assumed to be mounted
+	// under AutomountRoot
+	Mounts []Mount
+}
+
+// Mount tables tried by Load, in order. /proc/mounts is a symlink to
+// self/mounts, which cannot be followed over the UNC path from Windows.
+var mountTablePaths = []string{"proc/self/mounts", "proc/1/mounts", "proc/mounts"}
+
+// Load creates a Translator from the root file system of the distribution,
+// reading /etc/wsl.conf and the mount table if they exist.
+func Load(fsys fs.FS, distro string, uncRoot string) (*Translator, error) {
+	t := &Translator{
+		Distro:        distro,
+		UNCRoot:       uncRoot,
+		AutomountRoot: DefaultAutomountRoot,
+	}
+
+	wslConf, err := fs.ReadFile(fsys, "etc/wsl.conf")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Wrap(err, "failed to read /etc/wsl.conf")
+	} else if err == nil {
+		t.AutomountRoot = ParseAutomountRoot(string(wslConf))
+	}
+
+	mounts, err := loadMounts(fsys)
+	if err != nil {
+		return nil, err
+	}
+	t.Mounts = mounts
+	return t, nil
+}
+
+// loadMounts reads the first mount table that can be opened. nil is returned
+// if none of them exists.
+func loadMounts(fsys fs.FS) ([]Mount, error) {
+	var firstErr error
+	for _, mountTablePath := range mountTablePaths {
+		f, err := fsys.Open(mountTablePath)
+		if err != nil {
+			if firstErr == nil && !errors.Is(err, fs.ErrNotExist) {
+				firstErr = errors.Wrapf(err, "failed to open /%s", mountTablePath)
+			}
+			continue
+		}
+		defer f.Close()
+		return ParseMounts(f)
+	}
+	return nil, firstErr
+}
+
+// ParseAutomountRoot returns automount.root in the wsl.conf, or
+// DefaultAutomountRoot if not set. The result always ends with a slash.
+func ParseAutomountRoot(wslConf string) string {
+	root := DefaultAutomountRoot
+	if automount, ok := xdgini.ParseConfig(wslConf).Groups["automount"]; ok {
+		if entry, ok := automount.Entries["root"]; ok && entry.Value != "" {
+			root = strings.Trim(entry.Value, `"'`)
+		}
+	}
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root
+}
+
+// ParseMounts returns the Windows drives and shares in a mount table in the
+// format of /proc/mounts. Both the drvfs file system of WSL 1 and 9p mounts
+// with aname=drvfs of WSL 2 are recognized.
+func ParseMounts(r io.Reader) ([]Mount, error) {
+	mounts := []Mount{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		source, target, fsType, options := unescapeMountField(fields[0]), unescapeMountField(fields[1]), fields[2], fields[3]
+		isDrvfs := fsType == "drvfs" || (fsType == "9p" && strings.Contains(options, "aname=drvfs"))
+		if !isDrvfs {
+			continue
+		}
+		if len(source) == 2 && source[1] == ':' {
+			source += `\`
+		}
+		mounts = append(mounts, Mount{Source: source, Target: target})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read the mount table")
+	}
+	return mounts, nil
+}
+
+// unescapeMountField decodes the octal escapes like \040 in /proc/mounts.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var buf strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if n, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				buf.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		buf.WriteByte(field[i])
+	}
+	return buf.String()
+}
+
+// ToWindows translates the absolute Linux path to a Windows path.
+func (t *Translator) ToWindows(linuxPath string) (string, error) {
+	if !path.IsAbs(linuxPath) {
+		return "", errors.Wrapf(ErrUntranslatable, "relative path %s", linuxPath)
+	}
+	linuxPath = path.Clean(linuxPath)
+
+	if source, rest, ok := t.findMount(linuxPath); ok {
+		return joinWindows(source, rest)
+	}
+
+	if t.Distro == "" {
+		return "", errors.Wrapf(ErrUntranslatable, "unknown distribution for %s", linuxPath)
+	}
+	uncRoot := t.UNCRoot
+	if uncRoot == "" {
+		uncRoot = UNCRootLocalhost
+	}
+	return joinWindows(uncRoot+`\`+t.Distro, strings.TrimPrefix(linuxPath, "/"))
+}
+
+// findMount returns the Windows path of the mount containing the path and
+// the rest of the path in the mount.
+func (t *Translator) findMount(linuxPath string) (source string, rest string, ok bool) {
+	if t.Mounts == nil {
+		// Assume drives are mounted like /mnt/c
+		root := t.AutomountRoot
+		if root == "" {
+			root = DefaultAutomountRoot
+		}
+		if !strings.HasPrefix(linuxPath, root) {
+			return "", "", false
+		}
+		drive, rest, _ := strings.Cut(strings.TrimPrefix(linuxPath, root), "/")
+		if len(drive) != 1 || drive[0] < 'a' || drive[0] > 'z' {
+			return "", "", false
+		}
+		return strings.ToUpper(drive) + `:\`, rest, true
+	}
+
+	longest := -1
+	for i, mount := range t.Mounts {
+		target := path.Clean(mount.Target)
+		if linuxPath != target && !strings.HasPrefix(linuxPath, strings.TrimSuffix(target, "/")+"/") {
+			continue
+		}
+		if longest < 0 || len(target) > len(path.Clean(t.Mounts[longest].Target)) {
+			longest = i
+		}
+	}
+	if longest < 0 {
+		return "", "", false
+	}
+	target := path.Clean(t.Mounts[longest].Target)
+	rest = strings.TrimPrefix(strings.TrimPrefix(linuxPath, target), "/")
+	return t.Mounts[longest].Source, rest, true
+}
+
+// joinWindows appends the slash-separated relative path to the Windows
+// directory.
+func joinWindows(dir string, rest string) (string, error) {
+	if rest == "" {
+		return dir, nil
+	}
+	if strings.ContainsAny(rest, `\:*?"<>|`) {
+		// wslpath maps these characters to the private use area
+		return "", errors.Wrapf(ErrUntranslatable, "name not allowed in Windows: %s", rest)
+	}
+	return strings.TrimSuffix(dir, `\`) + `\` + strings.ReplaceAll(rest, "/", `\`), nil
+}
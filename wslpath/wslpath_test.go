@@ -0,0 +1,155 @@
+package wslpath_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/wslpath"
+)
+
+func TestToWindows(t *testing.T) {
+	testcases := []struct {
+		name    string
+		fixture string
+		uncRoot string
+		path    string
+		want    string
+	}{
+		{"drive", "wsl2", wslpath.UNCRootLocalhost, "/mnt/c/Users/me/a b.pdf", `C:\Users\me\a b.pdf`},
+		{"drive root", "wsl2", wslpath.UNCRootLocalhost, "/mnt/d", `D:\`},
+		{"unclean path", "wsl2", wslpath.UNCRootLocalhost, "/mnt/c//Users/./me/../me/", `C:\Users\me`},
+		{"linux path", "wsl2", wslpath.UNCRootLocalhost, "/home/me/a.pdf", `\\wsl.localhost\Ubuntu\home\me\a.pdf`},
+		{"legacy UNC root", "wsl2", wslpath.UNCRootLegacy, "/home/me/a.pdf", `\\wsl$\Ubuntu\home\me\a.pdf`},
+		{"root", "wsl2", wslpath.UNCRootLocalhost, "/", `\\wsl.localhost\Ubuntu`},
+		{"unmounted drive", "wsl2", wslpath.UNCRootLocalhost, "/mnt/e/a.pdf", `\\wsl.localhost\Ubuntu\mnt\e\a.pdf`},
+		{"not a drive", "wsl2", wslpath.UNCRootLocalhost, "/mnt/wsl/a.pdf", `\\wsl.localhost\Ubuntu\mnt\wsl\a.pdf`},
+		{"network share", "wsl2", wslpath.UNCRootLocalhost, "/home/me/share/a.pdf", `\\server\My Share\a.pdf`},
+		{"prefix of mount point", "wsl2", wslpath.UNCRootLocalhost, "/home/me/shared/a.pdf", `\\wsl.localhost\Ubuntu\home\me\shared\a.pdf`},
+		{"wsl1 drive", "wsl1", wslpath.UNCRootLocalhost, "/windir/c/a.pdf", `C:\a.pdf`},
+		{"wsl1 default root", "wsl1", wslpath.UNCRootLocalhost, "/mnt/c/a.pdf", `\\wsl.localhost\Ubuntu\mnt\c\a.pdf`},
+		{"no mount table", "custom-root", wslpath.UNCRootLocalhost, "/win/c/a.pdf", `C:\a.pdf`},
+		{"no mount table, linux path", "custom-root", wslpath.UNCRootLocalhost, "/mnt/c/a.pdf", `\\wsl.localhost\Ubuntu\mnt\c\a.pdf`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := wslpath.Load(os.DirFS(path.Join("testdata", tc.fixture)), "Ubuntu", tc.uncRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tr.ToWindows(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("ToWindows(%q) = %s, want %s", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+// unfollowedSymlinkFS fails to open the symlinks, like the UNC path of a
+// distribution does from Windows.
+type unfollowedSymlinkFS struct {
+	fstest.MapFS
+	symlinks []string
+}
+
+func (fsys unfollowedSymlinkFS) Open(name string) (fs.File, error) {
+	for _, symlink := range fsys.symlinks {
+		if name == symlink || strings.HasPrefix(name, symlink+"/") {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("the file cannot be accessed by the system")}
+		}
+	}
+	return fsys.MapFS.Open(name)
+}
+
+func TestLoadSymlinkedMounts(t *testing.T) {
+	mounts, err := os.ReadFile("testdata/wsl2/proc/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := unfollowedSymlinkFS{
+		MapFS: fstest.MapFS{
+			"proc/1/mounts": {Data: mounts},
+		},
+		symlinks: []string{"proc/mounts", "proc/self"},
+	}
+	tr, err := wslpath.Load(fsys, "Ubuntu", wslpath.UNCRootLocalhost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tr.Mounts), 3; got != want {
+		t.Errorf("len(Mounts) = %d, want %d", got, want)
+	}
+
+	// Fails rather than assuming the default mount points
+	fsys.MapFS = fstest.MapFS{}
+	if _, err := wslpath.Load(fsys, "Ubuntu", wslpath.UNCRootLocalhost); err == nil {
+		t.Error("Load() succeeded without a readable mount table, want error")
+	}
+}
+
+func TestToWindowsUntranslatable(t *testing.T) {
+	tr, err := wslpath.Load(fstest.MapFS{}, "Ubuntu", wslpath.UNCRootLocalhost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a.pdf", "../a.pdf", "/home/me/a:b.pdf", `/mnt/c/a\b.pdf`, "/home/me/what?.pdf"} {
+		if got, err := tr.ToWindows(p); !errors.Is(err, wslpath.ErrUntranslatable) {
+			t.Errorf("ToWindows(%q) = %q, %v, want ErrUntranslatable", p, got, err)
+		}
+	}
+
+	// Drives are translated even if the distribution is unknown
+	tr.Distro = ""
+	if got, err := tr.ToWindows("/mnt/c/a.pdf"); err != nil || got != `C:\a.pdf` {
+		t.Errorf("ToWindows(/mnt/c/a.pdf) = %q, %v, want C:\\a.pdf", got, err)
+	}
+	if got, err := tr.ToWindows("/home/me/a.pdf"); !errors.Is(err, wslpath.ErrUntranslatable) {
+		t.Errorf("ToWindows(/home/me/a.pdf) = %q, %v, want ErrUntranslatable", got, err)
+	}
+}
+
+func TestParseAutomountRoot(t *testing.T) {
+	testcases := []struct {
+		wslConf string
+		want    string
+	}{
+		{"", "/mnt/"},
+		{"[boot]\nsystemd=true\n", "/mnt/"},
+		{"[automount]\nroot = /windir/\n", "/windir/"},
+		{"[automount]\nroot=/\n", "/"},
+		{"[automount]\nroot = \"/win\"\n", "/win/"},
+		{"[automount]\nroot =\n", "/mnt/"},
+	}
+	for _, tc := range testcases {
+		if got := wslpath.ParseAutomountRoot(tc.wslConf); got != tc.want {
+			t.Errorf("ParseAutomountRoot(%q) = %q, want %q", tc.wslConf, got, tc.want)
+		}
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	mounts, err := os.ReadFile("testdata/wsl2/proc/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := wslpath.ParseMounts(strings.NewReader(string(mounts)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wslpath.Mount{
+		{Source: `C:\`, Target: "/mnt/c"},
+		{Source: `D:\`, Target: "/mnt/d"},
+		{Source: `\\server\My Share`, Target: "/home/me/share"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseMounts() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,166 @@
+package xdgini
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// DiagnosticKind classifies the problems found in a file.
+type DiagnosticKind int
+
+const (
+	// Violation of the Desktop Entry Specification found by
+	// ValidateDesktopEntry
+	KindSpec DiagnosticKind = iota
+	// Group header without the closing bracket, like "[Foo"
+	KindBrokenGroupHeader
+	// Group name with brackets or control characters
+	KindInvalidGroupName
+	// Line that is neither a group header, a key-value pair nor a comment
+	KindMissingEquals
+	KindDuplicateKey
+	KindDuplicateGroup
+	// Key-value pair before the first group header
+	KindKeyOutsideGroup
+	// Key with whitespace, brackets or non-ASCII characters
+	KindInvalidKey
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case KindSpec:
+		return "spec"
+	case KindBrokenGroupHeader:
+		return "broken-group-header"
+	case KindInvalidGroupName:
+		return "invalid-group-name"
+	case KindMissingEquals:
+		return "missing-equals"
+	case KindDuplicateKey:
+		return "duplicate-key"
+	case KindDuplicateGroup:
+		return "duplicate-group"
+	case KindKeyOutsideGroup:
+		return "key-outside-group"
+	case KindInvalidKey:
+		return "invalid-key"
+	default:
+		return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+	}
+}
+
+// Diagnostic is a problem found in a file.
+type Diagnostic struct {
+	// 1-based line number; 0 if the problem is not tied to a line
+	Line int
+	// 1-based byte offset in the line; 0 if the problem is not tied to a
+	// position in the line
+	Column   int
+	Severity Severity
+	Kind     DiagnosticKind
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	} else if d.Column == 0 {
+		return fmt.Sprintf("%d: %s: %s", d.Line, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+var (
+	genericKeyPattern = regexp.MustCompile(`^[\x21-\x3C\x3E-\x5A\x5C\x5E-\x7E]+(?:\[[\x21-\x3C\x3E-\x5A\x5C\x5E-\x7E]*\])?$`)
+	groupNamePattern  = regexp.MustCompile(`^[\x20-\x5A\x5C\x5E-\x7E]+$`)
+)
+
+// ParseConfigStrict is like ParseConfig, but also reports the syntax errors
+// that ParseConfig silently accepts, sorted by position. The returned config
+// is the same as the one from ParseConfig.
+func ParseConfigStrict(data string) (*Config, []Diagnostic) {
+	config := ParseConfig(data)
+	return config, checkSyntax(config)
+}
+
+// checkSyntax finds the syntax errors in the parsed lines of the config.
+func checkSyntax(c *Config) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(line int, column int, kind DiagnosticKind, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:     line,
+			Column:   column,
+			Severity: SeverityError,
+			Kind:     kind,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for name, group := range c.Groups {
+		for i, raw := range group.Raws {
+			if raw.LineNumber == 0 {
+				// Synthesized or dummy group for entries outside of any group
+				continue
+			}
+			tLine := strings.TrimRightFunc(raw.Line, unicode.IsSpace)
+			if !strings.HasSuffix(tLine, "]") {
+				report(raw.LineNumber, len(tLine)+1, KindBrokenGroupHeader, "broken group header %q", tLine)
+			} else if !groupNamePattern.MatchString(name) {
+				report(raw.LineNumber, 2, KindInvalidGroupName, "invalid group name %q", name)
+			}
+			if i > 0 {
+				report(raw.LineNumber, 1, KindDuplicateGroup, "duplicate group %q (first defined at line %d)", name, group.Raws[0].LineNumber)
+			}
+		}
+
+		for key, entry := range group.Entries {
+			for i, raw := range entry.Raws {
+				if raw.LineNumber == 0 {
+					continue
+				}
+				keyColumn := strings.IndexFunc(raw.Line, func(r rune) bool { return !unicode.IsSpace(r) }) + 1
+				if i > 0 {
+					report(raw.LineNumber, keyColumn, KindDuplicateKey, "duplicate key %q (first defined at line %d)", key, entry.Raws[0].LineNumber)
+				}
+				if name == "" && i == 0 {
+					report(raw.LineNumber, keyColumn, KindKeyOutsideGroup, "entry outside of any group")
+				}
+				if !strings.Contains(raw.Line, "=") {
+					tLine := strings.TrimRightFunc(raw.Line, unicode.IsSpace)
+					report(raw.LineNumber, len(tLine)+1, KindMissingEquals, "line %q is not a group header, a key-value pair or a comment", strings.TrimSpace(raw.Line))
+				} else if i == 0 && !genericKeyPattern.MatchString(key) {
+					report(raw.LineNumber, keyColumn, KindInvalidKey, "invalid key name %q", key)
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return diagnostics
+}
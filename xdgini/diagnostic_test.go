@@ -0,0 +1,95 @@
+package xdgini_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestParseConfigStrict(t *testing.T) {
+	testcases := []struct {
+		name        string
+		input       string
+		diagnostics []xdgini.Diagnostic
+	}{
+		{
+			name: "valid",
+			input: "# Comment\n" +
+				"[Default Applications]\n" +
+				"application/pdf=evince.desktop\n" +
+				"text/html = firefox.desktop;chromium.desktop;\n" +
+				"Name[de_DE@euro]=Foo\n" +
+				"\n" +
+				"[Added Associations]\n",
+			diagnostics: nil,
+		},
+		{
+			name: "broken lines",
+			input: "[Default Applications\n" +
+				"application/pdf=evince.desktop\n" +
+				"  Bar  \n" +
+				"[Foo]Bar]\n",
+			diagnostics: []xdgini.Diagnostic{
+				{Line: 1, Column: 22, Severity: xdgini.SeverityError, Kind: xdgini.KindBrokenGroupHeader, Message: `broken group header "[Default Applications"`},
+				{Line: 3, Column: 6, Severity: xdgini.SeverityError, Kind: xdgini.KindMissingEquals, Message: `line "Bar" is not a group header, a key-value pair or a comment`},
+				{Line: 4, Column: 2, Severity: xdgini.SeverityError, Kind: xdgini.KindInvalidGroupName, Message: `invalid group name "Foo]Bar"`},
+			},
+		},
+		{
+			name: "duplicates",
+			input: "[Foo]\n" +
+				"Key=Value1\n" +
+				"[Bar]\n" +
+				"[Foo]\n" +
+				" Key=Value2\n",
+			diagnostics: []xdgini.Diagnostic{
+				{Line: 4, Column: 1, Severity: xdgini.SeverityError, Kind: xdgini.KindDuplicateGroup, Message: `duplicate group "Foo" (first defined at line 1)`},
+				{Line: 5, Column: 2, Severity: xdgini.SeverityError, Kind: xdgini.KindDuplicateKey, Message: `duplicate key "Key" (first defined at line 2)`},
+			},
+		},
+		{
+			name: "keys",
+			input: "Key=Value\n" +
+				"[Foo]\n" +
+				"Some Key=Value\n" +
+				"=Value\n" +
+				"Ключ=Value\n",
+			diagnostics: []xdgini.Diagnostic{
+				{Line: 1, Column: 1, Severity: xdgini.SeverityError, Kind: xdgini.KindKeyOutsideGroup, Message: `entry outside of any group`},
+				{Line: 3, Column: 1, Severity: xdgini.SeverityError, Kind: xdgini.KindInvalidKey, Message: `invalid key name "Some Key"`},
+				{Line: 4, Column: 1, Severity: xdgini.SeverityError, Kind: xdgini.KindInvalidKey, Message: `invalid key name ""`},
+				{Line: 5, Column: 1, Severity: xdgini.SeverityError, Kind: xdgini.KindInvalidKey, Message: `invalid key name "Ключ"`},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, diagnostics := xdgini.ParseConfigStrict(tc.input)
+			if diff := cmp.Diff(tc.diagnostics, diagnostics); diff != "" {
+				t.Errorf("ParseConfigStrict() diagnostics mismatch (-want +got):\n%s", diff)
+			}
+			// The config is parsed as leniently as ParseConfig
+			if diff := cmp.Diff(tc.input, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	testcases := []struct {
+		diagnostic xdgini.Diagnostic
+		want       string
+	}{
+		{xdgini.Diagnostic{Severity: xdgini.SeverityError, Message: "foo"}, "error: foo"},
+		{xdgini.Diagnostic{Line: 3, Severity: xdgini.SeverityWarning, Message: "foo"}, "3: warning: foo"},
+		{xdgini.Diagnostic{Line: 3, Column: 5, Severity: xdgini.SeverityError, Message: "foo"}, "3:5: error: foo"},
+	}
+	for _, tc := range testcases {
+		if got := tc.diagnostic.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
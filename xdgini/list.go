@@ -0,0 +1,102 @@
+package xdgini
+
+import (
+	"slices"
+	"strings"
+)
+
+// SplitList splits a value of the "string(s)" type: items separated by
+// semicolons, where "\;" stands for a semicolon within an item. The trailing
+// semicolon is optional and empty items are dropped.
+func SplitList(value string) []string {
+	var items []string
+	var item strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			if value[i+1] == ';' {
+				item.WriteByte(';')
+			} else {
+				// Other escapes are left for the caller
+				item.WriteString(value[i : i+2])
+			}
+			i++
+		case value[i] == ';':
+			if item.Len() > 0 {
+				items = append(items, item.String())
+			}
+			item.Reset()
+		default:
+			item.WriteByte(value[i])
+		}
+	}
+	if item.Len() > 0 {
+		items = append(items, item.String())
+	}
+	return items
+}
+
+// JoinList is the inverse of SplitList. The result ends with a semicolon if
+// trailingSemicolon is true.
+func JoinList(items []string, trailingSemicolon bool) string {
+	escaped := make([]string, 0, len(items))
+	for _, item := range items {
+		escaped = append(escaped, strings.ReplaceAll(item, ";", `\;`))
+	}
+	value := strings.Join(escaped, ";")
+	if trailingSemicolon && value != "" {
+		value += ";"
+	}
+	return value
+}
+
+// hasTrailingSemicolon reports whether the list value is terminated by an
+// unescaped semicolon.
+func hasTrailingSemicolon(value string) bool {
+	if !strings.HasSuffix(value, ";") {
+		return false
+	}
+	backslashes := len(value) - 1 - len(strings.TrimRight(value[:len(value)-1], `\`))
+	return backslashes%2 == 0
+}
+
+// AppendToList adds the item at the end of the list value for the key,
+// creating the entry if needed, and reports whether the value changed.
+// The existing style of the trailing semicolon is kept; new entries are
+// terminated by a semicolon as recommended by the spec.
+func (g *ConfigGroup) AppendToList(key string, item string) bool {
+	entry, ok := g.Entries[key]
+	if !ok {
+		g.CreateEntry(key, JoinList([]string{item}, true))
+		return true
+	}
+	items := SplitList(entry.Value)
+	if slices.Contains(items, item) {
+		return false
+	}
+	entry.Value = JoinList(append(items, item), hasTrailingSemicolon(entry.Value) || len(items) == 0)
+	return true
+}
+
+// RemoveFromList removes the item from the list value for the key and
+// reports whether the value changed. The entry is deleted if the list
+// becomes empty.
+func (g *ConfigGroup) RemoveFromList(key string, item string) bool {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return false
+	}
+	items := SplitList(entry.Value)
+	remaining := slices.DeleteFunc(slices.Clone(items), func(i string) bool {
+		return i == item
+	})
+	if len(remaining) == len(items) {
+		return false
+	}
+	if len(remaining) == 0 {
+		g.DeleteEntry(key)
+		return true
+	}
+	entry.Value = JoinList(remaining, hasTrailingSemicolon(entry.Value))
+	return true
+}
@@ -0,0 +1,152 @@
+package xdgini_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestSplitList(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		items []string
+	}{
+		{
+			name:  "empty",
+			input: "",
+			items: nil,
+		},
+		{
+			name:  "trailing semicolon",
+			input: "a.desktop;b.desktop;",
+			items: []string{"a.desktop", "b.desktop"},
+		},
+		{
+			name:  "no trailing semicolon",
+			input: "a.desktop;b.desktop",
+			items: []string{"a.desktop", "b.desktop"},
+		},
+		{
+			name:  "empty items",
+			input: ";a.desktop;;b.desktop;",
+			items: []string{"a.desktop", "b.desktop"},
+		},
+		{
+			name:  "escaped semicolon",
+			input: `a\;b;c\\;d`,
+			items: []string{"a;b", `c\\`, "d"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			items := xdgini.SplitList(tc.input)
+			if diff := cmp.Diff(tc.items, items); diff != "" {
+				t.Errorf("SplitList(%q) mismatch (-want +got):\n%s", tc.input, diff)
+			}
+		})
+	}
+}
+
+func TestJoinList(t *testing.T) {
+	if got := xdgini.JoinList([]string{"a;b", "c"}, true); got != `a\;b;c;` {
+		t.Errorf("JoinList() = %q, want %q", got, `a\;b;c;`)
+	}
+	if got := xdgini.JoinList([]string{"a", "b"}, false); got != "a;b" {
+		t.Errorf("JoinList() = %q, want %q", got, "a;b")
+	}
+}
+
+func TestAppendToList(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		changed bool
+		output  string
+	}{
+		{
+			name:    "new entry",
+			input:   "[Added Associations]\n",
+			changed: true,
+			output:  "[Added Associations]\ntext/html=new.desktop;\n",
+		},
+		{
+			name:    "trailing semicolon",
+			input:   "[Added Associations]\ntext/html=a.desktop;\n",
+			changed: true,
+			output:  "[Added Associations]\ntext/html=a.desktop;new.desktop;\n",
+		},
+		{
+			name:    "no trailing semicolon",
+			input:   "[Added Associations]\ntext/html=a.desktop\n",
+			changed: true,
+			output:  "[Added Associations]\ntext/html=a.desktop;new.desktop\n",
+		},
+		{
+			name:    "already listed",
+			input:   "[Added Associations]\n# Comment\ntext/html=new.desktop;a.desktop\n",
+			changed: false,
+			output:  "[Added Associations]\n# Comment\ntext/html=new.desktop;a.desktop\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			if changed := config.Groups["Added Associations"].AppendToList("text/html", "new.desktop"); changed != tc.changed {
+				t.Errorf("AppendToList() = %v, want %v", changed, tc.changed)
+			}
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveFromList(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		changed bool
+		output  string
+	}{
+		{
+			name:    "one of items",
+			input:   "[Removed Associations]\ntext/html=a.desktop;old.desktop;b.desktop;\n",
+			changed: true,
+			output:  "[Removed Associations]\ntext/html=a.desktop;b.desktop;\n",
+		},
+		{
+			name:    "last item",
+			input:   "[Removed Associations]\ntext/html=a.desktop;old.desktop\n",
+			changed: true,
+			output:  "[Removed Associations]\ntext/html=a.desktop\n",
+		},
+		{
+			name:    "sole item",
+			input:   "[Removed Associations]\ntext/html=old.desktop;\ntext/plain=a.desktop;\n",
+			changed: true,
+			output:  "[Removed Associations]\ntext/plain=a.desktop;\n",
+		},
+		{
+			name:    "not listed",
+			input:   "[Removed Associations]\ntext/html=a.desktop;\n",
+			changed: false,
+			output:  "[Removed Associations]\ntext/html=a.desktop;\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			if changed := config.Groups["Removed Associations"].RemoveFromList("text/html", "old.desktop"); changed != tc.changed {
+				t.Errorf("RemoveFromList() = %v, want %v", changed, tc.changed)
+			}
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
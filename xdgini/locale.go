@@ -0,0 +1,110 @@
+package xdgini
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Locale is a locale in the form lang_COUNTRY.ENCODING@MODIFIER, where all
+// but lang are optional.
+type Locale struct {
+	Lang     string
+	Country  string
+	Encoding string
+	Modifier string
+}
+
+var localePattern = regexp.MustCompile(`^([a-z]{2,3})(?:_([A-Z]{2}))?(?:\.([A-Za-z0-9-]+))?(?:@([A-Za-z0-9]+))?$`)
+
+// ParseLocale parses a locale like "de_DE@euro" or "ja_JP.UTF-8".
+func ParseLocale(s string) (Locale, bool) {
+	m := localePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Locale{}, false
+	}
+	return Locale{Lang: m[1], Country: m[2], Encoding: m[3], Modifier: m[4]}, true
+}
+
+// String formats the locale as used in keys, without the encoding.
+func (l Locale) String() string {
+	s := l.Lang
+	if l.Country != "" {
+		s += "_" + l.Country
+	}
+	if l.Modifier != "" {
+		s += "@" + l.Modifier
+	}
+	return s
+}
+
+// candidates lists the locale suffixes to look up, in the matching order of
+// the spec: lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER and lang.
+func (l Locale) candidates() []string {
+	if l.Lang == "" {
+		return nil
+	}
+	var candidates []string
+	if l.Country != "" && l.Modifier != "" {
+		candidates = append(candidates, l.Lang+"_"+l.Country+"@"+l.Modifier)
+	}
+	if l.Country != "" {
+		candidates = append(candidates, l.Lang+"_"+l.Country)
+	}
+	if l.Modifier != "" {
+		candidates = append(candidates, l.Lang+"@"+l.Modifier)
+	}
+	return append(candidates, l.Lang)
+}
+
+// CurrentLocale returns the locale for messages from LC_ALL, LC_MESSAGES or
+// LANG. The zero Locale is returned for the C and POSIX locales.
+func CurrentLocale() Locale {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		locale, _ := ParseLocale(value)
+		return locale
+	}
+	return Locale{}
+}
+
+// SplitLocalizedKey splits a key like "Name[de_DE@euro]" into the base key
+// and the locale. The locale is empty for keys without a suffix.
+func SplitLocalizedKey(key string) (baseKey string, locale string) {
+	if !strings.HasSuffix(key, "]") {
+		return key, ""
+	}
+	bracket := strings.IndexByte(key, '[')
+	if bracket < 0 {
+		return key, ""
+	}
+	return key[:bracket], key[bracket+1 : len(key)-1]
+}
+
+// LocalizedKey returns the key with the locale suffix, like "Name[de]".
+func LocalizedKey(key string, locale string) string {
+	if locale == "" {
+		return key
+	}
+	return key + "[" + locale + "]"
+}
+
+// LocalizedString returns the unescaped value of a localestring key that
+// best matches the locale, falling back to the unlocalized key.
+func (g *ConfigGroup) LocalizedString(key string, locale Locale) (string, error) {
+	for _, candidate := range locale.candidates() {
+		if value, err := g.GetString(LocalizedKey(key, candidate)); err == nil {
+			return value, nil
+		}
+	}
+	value, err := g.GetString(key)
+	if err != nil {
+		return "", errors.Wrapf(err, "no value for %s in locale %s", key, locale)
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package xdgini_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestParseLocale(t *testing.T) {
+	testcases := []struct {
+		input  string
+		locale xdgini.Locale
+		ok     bool
+		str    string
+	}{
+		{"de", xdgini.Locale{Lang: "de"}, true, "de"},
+		{"ja_JP", xdgini.Locale{Lang: "ja", Country: "JP"}, true, "ja_JP"},
+		{"ja_JP.UTF-8", xdgini.Locale{Lang: "ja", Country: "JP", Encoding: "UTF-8"}, true, "ja_JP"},
+		{"sr@latin", xdgini.Locale{Lang: "sr", Modifier: "latin"}, true, "sr@latin"},
+		{"de_DE.ISO-8859-15@euro", xdgini.Locale{Lang: "de", Country: "DE", Encoding: "ISO-8859-15", Modifier: "euro"}, true, "de_DE@euro"},
+		{"C", xdgini.Locale{}, false, ""},
+		{"japanese", xdgini.Locale{}, false, ""},
+		{"", xdgini.Locale{}, false, ""},
+	}
+	for _, tc := range testcases {
+		locale, ok := xdgini.ParseLocale(tc.input)
+		if ok != tc.ok {
+			t.Errorf("ParseLocale(%q) ok = %v, want %v", tc.input, ok, tc.ok)
+		}
+		if diff := cmp.Diff(tc.locale, locale); diff != "" {
+			t.Errorf("ParseLocale(%q) mismatch (-want +got):\n%s", tc.input, diff)
+		}
+		if got := locale.String(); got != tc.str {
+			t.Errorf("ParseLocale(%q).String() = %q, want %q", tc.input, got, tc.str)
+		}
+	}
+}
+
+func TestSplitLocalizedKey(t *testing.T) {
+	testcases := []struct {
+		key     string
+		baseKey string
+		locale  string
+	}{
+		{"Name", "Name", ""},
+		{"Name[de_DE@euro]", "Name", "de_DE@euro"},
+		{"Name[", "Name[", ""},
+	}
+	for _, tc := range testcases {
+		baseKey, locale := xdgini.SplitLocalizedKey(tc.key)
+		if baseKey != tc.baseKey || locale != tc.locale {
+			t.Errorf("SplitLocalizedKey(%q) = (%q, %q), want (%q, %q)", tc.key, baseKey, locale, tc.baseKey, tc.locale)
+		}
+	}
+}
+
+func TestLocalizedString(t *testing.T) {
+	config := xdgini.ParseConfig("[Desktop Entry]\n" +
+		"Name=Default\n" +
+		"Name[sr]=sr\n" +
+		"Name[sr_YU]=sr_YU\n" +
+		"Name[sr@Latn]=sr@Latn\n" +
+		"Name[sr_YU@Latn]=sr_YU@Latn\n" +
+		"Name[de]=de\n" +
+		"Name[ja_JP]=ja\\sJP\n" +
+		"Comment[de]=de\n")
+	group := config.Groups["Desktop Entry"]
+	testcases := []struct {
+		locale string
+		want   string
+	}{
+		{"sr_YU.UTF-8@Latn", "sr_YU@Latn"},
+		{"sr_YU", "sr_YU"},
+		{"sr_CS@Latn", "sr@Latn"},
+		{"sr_CS", "sr"},
+		{"de_AT@euro", "de"},
+		{"ja_JP.UTF-8", "ja JP"},
+		{"ja", "Default"},
+		{"C", "Default"},
+	}
+	for _, tc := range testcases {
+		locale, _ := xdgini.ParseLocale(tc.locale)
+		got, err := group.LocalizedString("Name", locale)
+		if err != nil {
+			t.Errorf("LocalizedString(%q) error: %v", tc.locale, err)
+		} else if got != tc.want {
+			t.Errorf("LocalizedString(%q) = %q, want %q", tc.locale, got, tc.want)
+		}
+	}
+
+	// Falls back to the unlocalized key, which may be missing
+	locale, _ := xdgini.ParseLocale("en")
+	if _, err := group.LocalizedString("Comment", locale); !errors.Is(err, xdgini.ErrKeyNotFound) {
+		t.Errorf("LocalizedString(Comment) error = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestCurrentLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "de_DE.UTF-8")
+	t.Setenv("LANG", "ja_JP.UTF-8")
+	if got, want := xdgini.CurrentLocale(), (xdgini.Locale{Lang: "de", Country: "DE", Encoding: "UTF-8"}); got != want {
+		t.Errorf("CurrentLocale() = %v, want %v", got, want)
+	}
+	t.Setenv("LC_ALL", "C")
+	if got := xdgini.CurrentLocale(); got != (xdgini.Locale{}) {
+		t.Errorf("CurrentLocale() = %v, want zero", got)
+	}
+}
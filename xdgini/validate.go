@@ -0,0 +1,342 @@
+package xdgini
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type valueType int
+
+const (
+	valueString valueType = iota
+	valueLocaleString
+	valueIconString
+	valueBoolean
+	valueStrings
+	valueLocaleStrings
+)
+
+type keySpec struct {
+	valueType valueType
+	// Types of desktop entries the key is allowed for; nil for all types
+	types []string
+}
+
+var applicationOnly = []string{"Application"}
+
+// Keys defined in the Desktop Entry Specification 1.5
+var desktopEntryKeys = map[string]keySpec{
+	"Type":                 {valueString, nil},
+	"Version":              {valueString, nil},
+	"Name":                 {valueLocaleString, nil},
+	"GenericName":          {valueLocaleString, nil},
+	"NoDisplay":            {valueBoolean, nil},
+	"Comment":              {valueLocaleString, nil},
+	"Icon":                 {valueIconString, nil},
+	"Hidden":               {valueBoolean, nil},
+	"OnlyShowIn":           {valueStrings, nil},
+	"NotShowIn":            {valueStrings, nil},
+	"DBusActivatable":      {valueBoolean, applicationOnly},
+	"TryExec":              {valueString, applicationOnly},
+	"Exec":                 {valueString, applicationOnly},
+	"Path":                 {valueString, applicationOnly},
+	"Terminal":             {valueBoolean, applicationOnly},
+	"Actions":              {valueStrings, applicationOnly},
+	"MimeType":             {valueStrings, applicationOnly},
+	"Categories":           {valueStrings, applicationOnly},
+	"Implements":           {valueStrings, nil},
+	"Keywords":             {valueLocaleStrings, applicationOnly},
+	"StartupNotify":        {valueBoolean, applicationOnly},
+	"StartupWMClass":       {valueString, applicationOnly},
+	"URL":                  {valueString, []string{"Link"}},
+	"PrefersNonDefaultGPU": {valueBoolean, applicationOnly},
+	"SingleMainWindow":     {valueBoolean, applicationOnly},
+}
+
+// Keys allowed in "Desktop Action" groups
+var desktopActionKeys = map[string]keySpec{
+	"Name": {valueLocaleString, nil},
+	"Icon": {valueIconString, nil},
+	"Exec": {valueString, nil},
+}
+
+var desktopEntryVersions = []string{"1.0", "1.1", "1.2", "1.3", "1.4", "1.5"}
+
+var (
+	keyPattern = regexp.MustCompile(`^([A-Za-z0-9-]+)(?:\[([^\]]*)\])?$`)
+	// Type and subtype are restricted-name in RFC 6838
+	mimeTypePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]*/[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]*$`)
+)
+
+// IsValidMIMEType reports whether s is a MIME type like "text/plain", as
+// accepted in MimeType of desktop entries.
+func IsValidMIMEType(s string) bool {
+	return mimeTypePattern.MatchString(s)
+}
+
+// ValidateDesktopEntry checks the config against the Desktop Entry
+// Specification, like desktop-file-validate. The diagnostics are sorted by
+// line number.
+func ValidateDesktopEntry(c *Config) []Diagnostic {
+	v := &validator{diagnostics: checkSyntax(c)}
+
+	type groupItem struct {
+		name  string
+		group *ConfigGroup
+	}
+	var groupItems []groupItem
+	for name, group := range c.Groups {
+		groupItems = append(groupItems, groupItem{name, group})
+	}
+	slices.SortFunc(groupItems, func(a, b groupItem) int {
+		return groupOrder(a.group) - groupOrder(b.group)
+	})
+
+	if len(groupItems) == 0 || groupItems[0].name != "Desktop Entry" {
+		line := 0
+		if len(groupItems) > 0 && len(groupItems[0].group.Raws) > 0 {
+			line = groupItems[0].group.Raws[0].LineNumber
+		}
+		v.errorf(line, "first group must be \"Desktop Entry\"")
+	}
+
+	var actions []string
+	entryType := ""
+	if desktopEntry, ok := c.Groups["Desktop Entry"]; ok {
+		if typeEntry, ok := desktopEntry.Entries["Type"]; ok {
+			entryType = typeEntry.Value
+		}
+		if actionsEntry, ok := desktopEntry.Entries["Actions"]; ok {
+			actions = SplitList(actionsEntry.Value)
+		}
+	}
+
+	for _, item := range groupItems {
+		switch {
+		case item.name == "":
+			// Reported as a syntax error
+		case item.name == "Desktop Entry":
+			v.validateGroup(item.group, desktopEntryKeys, entryType)
+			v.validateDesktopEntryGroup(item.group, entryType)
+		case strings.HasPrefix(item.name, "Desktop Action "):
+			action := strings.TrimPrefix(item.name, "Desktop Action ")
+			line := groupLine(item.group)
+			if !slices.Contains(actions, action) {
+				v.errorf(line, "action %q is not listed in the Actions key", action)
+			}
+			v.validateGroup(item.group, desktopActionKeys, "")
+			if _, ok := item.group.Entries["Name"]; !ok {
+				v.errorf(line, "required key \"Name\" is missing in group %q", item.name)
+			}
+		case strings.HasPrefix(item.name, "X-"):
+			// Extension groups are not checked
+		default:
+			v.errorf(groupLine(item.group), "unknown group %q; groups extending the format must start with \"X-\"", item.name)
+		}
+	}
+
+	for _, action := range actions {
+		if _, ok := c.Groups["Desktop Action "+action]; !ok {
+			v.errorf(entryLine(c.Groups["Desktop Entry"], "Actions"), "group for action %q is missing", action)
+		}
+	}
+
+	slices.SortStableFunc(v.diagnostics, func(a, b Diagnostic) int {
+		return a.Line - b.Line
+	})
+	return v.diagnostics
+}
+
+type validator struct {
+	diagnostics []Diagnostic
+}
+
+func (v *validator) errorf(line int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Line: line, Severity: SeverityError, Kind: KindSpec, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(line int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Line: line, Severity: SeverityWarning, Kind: KindSpec, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateGroup checks the key names and the syntax of the values.
+func (v *validator) validateGroup(group *ConfigGroup, keys map[string]keySpec, entryType string) {
+	for key, entry := range group.Entries {
+		line := entryLine(group, key)
+		if (len(entry.Raws) > 0 && !strings.Contains(entry.Raws[0].Line, "=")) || !genericKeyPattern.MatchString(key) {
+			// Reported as a syntax error
+			continue
+		}
+		m := keyPattern.FindStringSubmatch(key)
+		if m == nil {
+			v.errorf(line, "invalid key name %q", key)
+			continue
+		}
+		baseKey, locale := m[1], m[2]
+		hasLocale := strings.Contains(key, "[")
+		if _, ok := ParseLocale(locale); hasLocale && !ok {
+			v.errorf(line, "invalid locale %q in key %q", locale, key)
+		}
+		if strings.HasPrefix(baseKey, "X-") {
+			continue
+		}
+		spec, ok := keys[baseKey]
+		if !ok {
+			v.errorf(line, "unknown key %q; keys extending the format must start with \"X-\"", baseKey)
+			continue
+		}
+		if spec.types != nil && entryType != "" && !slices.Contains(spec.types, entryType) {
+			v.warnf(line, "key %q is only meaningful for Type=%s", baseKey, strings.Join(spec.types, ", "))
+		}
+		if hasLocale && spec.valueType != valueLocaleString && spec.valueType != valueLocaleStrings && spec.valueType != valueIconString {
+			v.errorf(line, "key %q cannot be localized", baseKey)
+		}
+		v.validateValue(line, key, entry.Value, spec.valueType)
+	}
+}
+
+func (v *validator) validateValue(line int, key string, value string, valueType valueType) {
+	switch valueType {
+	case valueBoolean:
+		if value != "true" && value != "false" {
+			if value == "0" || value == "1" {
+				v.errorf(line, "value %q for boolean key %q must be \"true\" or \"false\"; numeric booleans are deprecated", value, key)
+			} else {
+				v.errorf(line, "value %q for boolean key %q must be \"true\" or \"false\"", value, key)
+			}
+		}
+		return
+	case valueString, valueStrings:
+		for _, r := range value {
+			if r < 0x20 || r >= 0x7F {
+				v.errorf(line, "value for key %q contains a non-ASCII or control character; use a locale string key instead", key)
+				break
+			}
+		}
+	}
+	isList := valueType == valueStrings || valueType == valueLocaleStrings
+	if err := checkEscapes(value, isList); err != "" {
+		v.errorf(line, "value for key %q contains %s", key, err)
+	}
+	if isList && value != "" && !hasTrailingSemicolon(value) {
+		v.warnf(line, "list value for key %q does not end with a semicolon", key)
+	}
+}
+
+// checkEscapes returns a description of the invalid escape sequence, if any.
+func checkEscapes(value string, isList bool) string {
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			continue
+		}
+		if i+1 >= len(value) {
+			return "a trailing backslash"
+		}
+		switch value[i+1] {
+		case 's', 'n', 't', 'r', '\\':
+		case ';':
+			if !isList {
+				return "an escaped semicolon outside of a list"
+			}
+		default:
+			return fmt.Sprintf("an invalid escape sequence %q", value[i:i+2])
+		}
+		i++
+	}
+	return ""
+}
+
+// validateDesktopEntryGroup checks the keys required for the type.
+func (v *validator) validateDesktopEntryGroup(group *ConfigGroup, entryType string) {
+	line := groupLine(group)
+	for _, key := range []string{"Type", "Name"} {
+		if _, ok := group.Entries[key]; !ok {
+			v.errorf(line, "required key %q is missing", key)
+		}
+	}
+	switch entryType {
+	case "", "Directory":
+	case "Application":
+		dbusActivatable := false
+		if entry, ok := group.Entries["DBusActivatable"]; ok {
+			dbusActivatable = entry.Value == "true"
+		}
+		if _, ok := group.Entries["Exec"]; !ok && !dbusActivatable {
+			v.errorf(line, "required key \"Exec\" is missing for Type=Application")
+		}
+	case "Link":
+		if _, ok := group.Entries["URL"]; !ok {
+			v.errorf(line, "required key \"URL\" is missing for Type=Link")
+		}
+	default:
+		v.errorf(entryLine(group, "Type"), "unknown type %q; must be one of Application, Link and Directory", entryType)
+	}
+
+	if entry, ok := group.Entries["Version"]; ok && !slices.Contains(desktopEntryVersions, entry.Value) {
+		v.errorf(entryLine(group, "Version"), "unknown version %q of the Desktop Entry Specification", entry.Value)
+	}
+	if entry, ok := group.Entries["Exec"]; ok {
+		v.validateExec(entryLine(group, "Exec"), entry.Value)
+	}
+	if entry, ok := group.Entries["MimeType"]; ok {
+		for _, mimeType := range SplitList(entry.Value) {
+			if !IsValidMIMEType(mimeType) {
+				v.errorf(entryLine(group, "MimeType"), "invalid MIME type %q", mimeType)
+			}
+		}
+	}
+}
+
+// validateExec checks the field codes in the Exec key.
+func (v *validator) validateExec(line int, exec string) {
+	var fileCodes []string
+	for i := 0; i < len(exec); i++ {
+		if exec[i] != '%' {
+			continue
+		}
+		if i+1 >= len(exec) {
+			v.errorf(line, "Exec ends with an incomplete field code")
+			break
+		}
+		code := exec[i : i+2]
+		i++
+		switch code[1] {
+		case '%', 'i', 'c', 'k':
+		case 'f', 'F', 'u', 'U':
+			fileCodes = append(fileCodes, code)
+		case 'd', 'D', 'n', 'N', 'v', 'm':
+			v.warnf(line, "deprecated field code %q in Exec", code)
+		default:
+			v.errorf(line, "invalid field code %q in Exec", code)
+		}
+	}
+	if len(fileCodes) > 1 {
+		v.errorf(line, "Exec contains more than one of %%f, %%F, %%u and %%U: %s", strings.Join(fileCodes, ", "))
+	}
+}
+
+func groupOrder(group *ConfigGroup) int {
+	if len(group.Raws) > 0 {
+		return group.Raws[0].Order
+	}
+	return 0
+}
+
+func groupLine(group *ConfigGroup) int {
+	if len(group.Raws) > 0 {
+		return group.Raws[0].LineNumber
+	}
+	return 0
+}
+
+func entryLine(group *ConfigGroup, key string) int {
+	if group == nil {
+		return 0
+	}
+	if entry, ok := group.Entries[key]; ok && len(entry.Raws) > 0 {
+		return entry.Raws[0].LineNumber
+	}
+	return groupLine(group)
+}
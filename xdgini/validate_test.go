@@ -0,0 +1,195 @@
+package xdgini_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestValidateDesktopEntry(t *testing.T) {
+	testcases := []struct {
+		name        string
+		input       string
+		diagnostics []string
+	}{
+		{
+			name: "valid",
+			input: "[Desktop Entry]\n" +
+				"Type=Application\n" +
+				"Version=1.5\n" +
+				"Name=Foo\n" +
+				"Name[ja]=フー\n" +
+				"Comment=Opens\\sfiles\n" +
+				"Exec=foo --bar %f\n" +
+				"MimeType=text/html;application/pdf;\n" +
+				"Actions=new-window;\n" +
+				"X-Foo-Version=1\n" +
+				"\n" +
+				"[Desktop Action new-window]\n" +
+				"Name=New Window\n" +
+				"Exec=foo --new-window\n",
+			diagnostics: nil,
+		},
+		{
+			name:  "empty",
+			input: "",
+			diagnostics: []string{
+				"error: first group must be \"Desktop Entry\"",
+			},
+		},
+		{
+			name: "missing keys",
+			input: "# Comment\n" +
+				"[Desktop Entry]\n" +
+				"Type=Application\n",
+			diagnostics: []string{
+				"2: error: required key \"Name\" is missing",
+				"2: error: required key \"Exec\" is missing for Type=Application",
+			},
+		},
+		{
+			name: "link",
+			input: "[Desktop Entry]\n" +
+				"Type=Link\n" +
+				"Name=Foo\n" +
+				"Terminal=false\n",
+			diagnostics: []string{
+				"1: error: required key \"URL\" is missing for Type=Link",
+				"4: warning: key \"Terminal\" is only meaningful for Type=Application",
+			},
+		},
+		{
+			name: "value syntax",
+			input: "[Desktop Entry]\n" +
+				"Type=Application\n" +
+				"Name=Foo\n" +
+				"Version=0.1.2\n" +
+				"Exec=foo\n" +
+				"NoDisplay=yes\n" +
+				"Terminal=1\n" +
+				"Comment=a\\qb\n" +
+				"MimeType=text/html;not-a-mime-type\n" +
+				"Path=/home/café\n",
+			diagnostics: []string{
+				"4: error: unknown version \"0.1.2\" of the Desktop Entry Specification",
+				"6: error: value \"yes\" for boolean key \"NoDisplay\" must be \"true\" or \"false\"",
+				"7: error: value \"1\" for boolean key \"Terminal\" must be \"true\" or \"false\"; numeric booleans are deprecated",
+				"8: error: value for key \"Comment\" contains an invalid escape sequence \"\\\\q\"",
+				"9: warning: list value for key \"MimeType\" does not end with a semicolon",
+				"9: error: invalid MIME type \"not-a-mime-type\"",
+				"10: error: value for key \"Path\" contains a non-ASCII or control character; use a locale string key instead",
+			},
+		},
+		{
+			name: "keys",
+			input: "[Desktop Entry]\n" +
+				"Type=Application\n" +
+				"Name=Foo\n" +
+				"Exec=foo\n" +
+				"Foo=bar\n" +
+				"Exec[ja]=foo\n" +
+				"Name[japanese]=Foo\n" +
+				"Name=Bar\n" +
+				"Broken\n",
+			diagnostics: []string{
+				"5: error: unknown key \"Foo\"; keys extending the format must start with \"X-\"",
+				"6: error: key \"Exec\" cannot be localized",
+				"7: error: invalid locale \"japanese\" in key \"Name[japanese]\"",
+				"8:1: error: duplicate key \"Name\" (first defined at line 3)",
+				"9:7: error: line \"Broken\" is not a group header, a key-value pair or a comment",
+			},
+		},
+		{
+			name: "exec field codes",
+			input: "[Desktop Entry]\n" +
+				"Type=Application\n" +
+				"Name=Foo\n" +
+				"Exec=foo %f %U %d %z 100%%\n",
+			diagnostics: []string{
+				"4: warning: deprecated field code \"%d\" in Exec",
+				"4: error: invalid field code \"%z\" in Exec",
+				"4: error: Exec contains more than one of %f, %F, %u and %U: %f, %U",
+			},
+		},
+		{
+			name: "groups",
+			input: "Key=Value\n" +
+				"[Desktop Entry]\n" +
+				"Type=Directory\n" +
+				"Name=Foo\n" +
+				"[Desktop Action foo]\n" +
+				"Name=Foo\n" +
+				"[X-Extension]\n" +
+				"Anything=goes\n" +
+				"[Other]\n" +
+				"[Broken\n",
+			diagnostics: []string{
+				"error: first group must be \"Desktop Entry\"",
+				"1:1: error: entry outside of any group",
+				"5: error: action \"foo\" is not listed in the Actions key",
+				"9: error: unknown group \"Other\"; groups extending the format must start with \"X-\"",
+				"10:8: error: broken group header \"[Broken\"",
+				"10: error: unknown group \"Broken\"; groups extending the format must start with \"X-\"",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var diagnostics []string
+			for _, d := range xdgini.ValidateDesktopEntry(xdgini.ParseConfig(tc.input)) {
+				diagnostics = append(diagnostics, d.String())
+			}
+			if diff := cmp.Diff(tc.diagnostics, diagnostics); diff != "" {
+				t.Errorf("ValidateDesktopEntry() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateDesktopEntryBuiltInCode(t *testing.T) {
+	config := &xdgini.Config{Groups: map[string]*xdgini.ConfigGroup{}}
+	group := config.CreateGroup("Desktop Entry")
+	group.CreateEntry("Type", "Application")
+	group.CreateEntry("Version", "0.9")
+	group.CreateEntry("Name", "Foo")
+	group.CreateEntry("Exec", "foo %f %u")
+	group.CreateEntry("MimeType", "text/html;text;")
+
+	var diagnostics []string
+	for _, d := range xdgini.ValidateDesktopEntry(config) {
+		diagnostics = append(diagnostics, d.String())
+	}
+	want := []string{
+		"error: Exec contains more than one of %f, %F, %u and %U: %f, %u",
+		"error: invalid MIME type \"text\"",
+		"error: unknown version \"0.9\" of the Desktop Entry Specification",
+	}
+	if diff := cmp.Diff(want, diagnostics, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("ValidateDesktopEntry() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsValidMIMEType(t *testing.T) {
+	testcases := []struct {
+		mimeType string
+		valid    bool
+	}{
+		{"text/plain", true},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", true},
+		{"image/svg+xml", true},
+		{"x-scheme-handler/mailto", true},
+		{"text", false},
+		{"text/", false},
+		{"/plain", false},
+		{"text/plain/x", false},
+		{"text/plain; charset=utf-8", false},
+	}
+	for _, tc := range testcases {
+		if got := xdgini.IsValidMIMEType(tc.mimeType); got != tc.valid {
+			t.Errorf("IsValidMIMEType(%q) = %v, want %v", tc.mimeType, got, tc.valid)
+		}
+	}
+}
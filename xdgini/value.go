@@ -0,0 +1,173 @@
+package xdgini
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrKeyNotFound is returned by the typed getters if the key does not exist.
+var ErrKeyNotFound = errors.New("key not found")
+
+// UnescapeString decodes the escape sequences \s, \n, \t, \r and \\.
+// Other backslashes are kept as they are.
+func UnescapeString(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 >= len(value) {
+			buf.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case 's':
+			buf.WriteByte(' ')
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case '\\':
+			buf.WriteByte('\\')
+		default:
+			buf.WriteString(value[i : i+2])
+		}
+		i++
+	}
+	return buf.String()
+}
+
+// EscapeString is the inverse of UnescapeString. Leading and trailing spaces
+// are escaped as \s since they are otherwise stripped by the parser.
+func EscapeString(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case ' ':
+			if i == 0 || i == len(s)-1 {
+				buf.WriteString(`\s`)
+			} else {
+				buf.WriteByte(' ')
+			}
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String()
+}
+
+// setValue updates the value, leaving the entry as is if equal so that the
+// original line is kept byte-for-byte.
+func (g *ConfigGroup) setValue(key string, value string) {
+	if entry, ok := g.Entries[key]; ok && entry.Value == value {
+		return
+	}
+	g.CreateEntry(key, value)
+}
+
+// GetString returns the unescaped value of a string or localestring key.
+func (g *ConfigGroup) GetString(key string) (string, error) {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return "", errors.Wrapf(ErrKeyNotFound, "%s", key)
+	}
+	return UnescapeString(entry.Value), nil
+}
+
+// SetString sets the value of a string or localestring key, escaping it as
+// needed. The entry is left untouched if the value does not change.
+func (g *ConfigGroup) SetString(key string, value string) {
+	if current, err := g.GetString(key); err == nil && current == value {
+		return
+	}
+	g.setValue(key, EscapeString(value))
+}
+
+// GetBool returns the value of a boolean key, which must be "true" or
+// "false".
+func (g *ConfigGroup) GetBool(key string) (bool, error) {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return false, errors.Wrapf(ErrKeyNotFound, "%s", key)
+	}
+	switch entry.Value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, errors.Errorf("invalid boolean value %q for key %q", entry.Value, key)
+	}
+}
+
+func (g *ConfigGroup) SetBool(key string, value bool) {
+	g.setValue(key, strconv.FormatBool(value))
+}
+
+// GetNumber returns the value of a numeric key.
+func (g *ConfigGroup) GetNumber(key string) (float64, error) {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return 0, errors.Wrapf(ErrKeyNotFound, "%s", key)
+	}
+	value, err := strconv.ParseFloat(entry.Value, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid numeric value %q for key %q", entry.Value, key)
+	}
+	return value, nil
+}
+
+// SetNumber sets the value of a numeric key. The entry is left untouched if
+// it already has the value in another notation, like "1.0" for 1.
+func (g *ConfigGroup) SetNumber(key string, value float64) {
+	if current, err := g.GetNumber(key); err == nil && current == value {
+		return
+	}
+	g.setValue(key, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// GetStringList returns the unescaped items of a strings or localestrings
+// key.
+func (g *ConfigGroup) GetStringList(key string) ([]string, error) {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return nil, errors.Wrapf(ErrKeyNotFound, "%s", key)
+	}
+	items := SplitList(entry.Value)
+	for i, item := range items {
+		items[i] = UnescapeString(item)
+	}
+	return items, nil
+}
+
+// SetStringList sets the items of a strings or localestrings key. The entry
+// is left untouched if the items do not change; otherwise the existing style
+// of the trailing semicolon is kept, and new entries end with a semicolon.
+func (g *ConfigGroup) SetStringList(key string, items []string) {
+	trailingSemicolon := true
+	if entry, ok := g.Entries[key]; ok {
+		current, _ := g.GetStringList(key)
+		if slices.Equal(current, items) {
+			return
+		}
+		trailingSemicolon = hasTrailingSemicolon(entry.Value) || entry.Value == ""
+	}
+	escaped := make([]string, 0, len(items))
+	for _, item := range items {
+		escaped = append(escaped, EscapeString(item))
+	}
+	g.setValue(key, JoinList(escaped, trailingSemicolon))
+}
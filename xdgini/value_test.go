@@ -0,0 +1,109 @@
+package xdgini_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qnighy/wsl-open-proxy/xdgini"
+)
+
+func TestEscapeString(t *testing.T) {
+	testcases := []struct {
+		raw     string
+		escaped string
+	}{
+		{"foo bar", "foo bar"},
+		{" foo ", `\sfoo\s`},
+		{"a\nb\tc\rd", `a\nb\tc\rd`},
+		{`C:\Windows`, `C:\\Windows`},
+		{"a;b", "a;b"},
+	}
+	for _, tc := range testcases {
+		if got := xdgini.EscapeString(tc.raw); got != tc.escaped {
+			t.Errorf("EscapeString(%q) = %q, want %q", tc.raw, got, tc.escaped)
+		}
+		if got := xdgini.UnescapeString(tc.escaped); got != tc.raw {
+			t.Errorf("UnescapeString(%q) = %q, want %q", tc.escaped, got, tc.raw)
+		}
+	}
+	// Unknown escapes are kept
+	if got := xdgini.UnescapeString(`a\qb\`); got != `a\qb\` {
+		t.Errorf("UnescapeString() = %q, want %q", got, `a\qb\`)
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	config := xdgini.ParseConfig("[Desktop Entry]\n" +
+		"Name=Foo\\sBar\\\\Baz\n" +
+		"NoDisplay=true\n" +
+		"Terminal=yes\n" +
+		"X-Size=1.5\n" +
+		"MimeType=text/html;text/x\\;y;a\\sb;\n")
+	group := config.Groups["Desktop Entry"]
+
+	if got, err := group.GetString("Name"); err != nil || got != `Foo Bar\Baz` {
+		t.Errorf("GetString() = %q, %v", got, err)
+	}
+	if got, err := group.GetBool("NoDisplay"); err != nil || !got {
+		t.Errorf("GetBool() = %v, %v", got, err)
+	}
+	if _, err := group.GetBool("Terminal"); err == nil {
+		t.Errorf("GetBool() for an invalid value succeeded")
+	}
+	if got, err := group.GetNumber("X-Size"); err != nil || got != 1.5 {
+		t.Errorf("GetNumber() = %v, %v", got, err)
+	}
+	got, err := group.GetStringList("MimeType")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"text/html", "text/x;y", "a b"}, got); diff != "" {
+		t.Errorf("GetStringList() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := group.GetString("Missing"); !errors.Is(err, xdgini.ErrKeyNotFound) {
+		t.Errorf("GetString() for a missing key = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestTypedSetters(t *testing.T) {
+	input := "[Desktop Entry]\n" +
+		"Name = Foo\\sBar\n" +
+		"NoDisplay=true\n" +
+		"X-Size=1.0\n" +
+		"MimeType=text/html;application/pdf\n" +
+		"Keywords=a;b;\n"
+
+	t.Run("unchanged", func(t *testing.T) {
+		config := xdgini.ParseConfig(input)
+		group := config.Groups["Desktop Entry"]
+		group.SetString("Name", "Foo Bar")
+		group.SetBool("NoDisplay", true)
+		group.SetNumber("X-Size", 1)
+		group.SetStringList("MimeType", []string{"text/html", "application/pdf"})
+		if diff := cmp.Diff(input, config.String()); diff != "" {
+			t.Errorf("String() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		config := xdgini.ParseConfig(input)
+		group := config.Groups["Desktop Entry"]
+		group.SetString("Name", " Foo\nBar")
+		group.SetBool("NoDisplay", false)
+		group.SetNumber("X-Size", 2.5)
+		group.SetStringList("MimeType", []string{"text/html", "text/x;y"})
+		group.SetStringList("Keywords", []string{"c"})
+		group.SetStringList("Categories", []string{"Utility"})
+		want := "[Desktop Entry]\n" +
+			"Name=\\sFoo\\nBar\n" +
+			"NoDisplay=false\n" +
+			"X-Size=2.5\n" +
+			"MimeType=text/html;text/x\\;y\n" +
+			"Keywords=c;\n" +
+			"Categories=Utility;\n"
+		if diff := cmp.Diff(want, config.String()); diff != "" {
+			t.Errorf("String() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
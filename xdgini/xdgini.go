@@ -9,6 +9,22 @@ import (
 type Config struct {
 	Groups map[string]*ConfigGroup
 	EndRaw *RawLineStyle
+	// Line ending used for synthesized lines; "\n" if empty. ParseConfig
+	// sets it to "\r\n" for files mostly using CRLF line endings.
+	LineEnding string
+	// Whether the file starts with a UTF-8 byte order mark
+	BOM bool
+	// Whether the last line of the file lacks a line ending
+	NoFinalNewline bool
+}
+
+const utf8BOM = "\ufeff"
+
+func (c *Config) lineEnding() string {
+	if c.LineEnding == "" {
+		return "\n"
+	}
+	return c.LineEnding
 }
 
 func (c *Config) CreateGroup(name string) *ConfigGroup {
@@ -23,6 +39,95 @@ func (c *Config) CreateGroup(name string) *ConfigGroup {
 	}
 }
 
+// DeleteGroup removes the group with all its entries and reports whether it
+// existed.
+//
+// The comments directly above the group header, with no blank line in
+// between, describe the group and are removed with it. As with DeleteEntry,
+// other comments and blank lines attached to the removed lines are kept: they
+// are moved to the line preceding the group, or to the line following it if
+// the group comes first.
+func (c *Config) DeleteGroup(name string) bool {
+	group, ok := c.Groups[name]
+	if !ok {
+		return false
+	}
+	delete(c.Groups, name)
+
+	var raws []*RawLineStyle
+	raws = append(raws, group.Raws...)
+	for _, entry := range group.Entries {
+		raws = append(raws, entry.Raws...)
+	}
+	slices.SortFunc(raws, func(a, b *RawLineStyle) int {
+		return a.Order - b.Order
+	})
+	var comments []string
+	for _, raw := range raws {
+		if slices.Contains(group.Raws, raw) {
+			comments = append(comments, detachedComments(raw.LeadingComments)...)
+		} else {
+			comments = append(comments, raw.LeadingComments...)
+		}
+		comments = append(comments, raw.TrailingComments...)
+	}
+	if len(comments) == 0 || len(raws) == 0 {
+		return true
+	}
+	if prev := c.rawBefore(raws[0].Order); prev != nil {
+		prev.TrailingComments = append(prev.TrailingComments, comments...)
+	} else if next := c.rawAfter(raws[len(raws)-1].Order); next != nil {
+		next.LeadingComments = append(comments, next.LeadingComments...)
+	} else if c.EndRaw != nil {
+		c.EndRaw.TrailingComments = append(comments, c.EndRaw.TrailingComments...)
+	}
+	return true
+}
+
+// detachedComments returns the leading comments up to the last blank line,
+// leaving out the comment block directly above the line.
+func detachedComments(comments []string) []string {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if strings.TrimSpace(comments[i]) == "" {
+			return comments[:i+1]
+		}
+	}
+	return nil
+}
+
+// rawBefore returns the last line in the file that precedes the given order.
+func (c *Config) rawBefore(order int) *RawLineStyle {
+	var prev *RawLineStyle
+	for _, group := range c.Groups {
+		if raw := group.rawBefore(order); raw != nil && (prev == nil || raw.Order > prev.Order) {
+			prev = raw
+		}
+	}
+	return prev
+}
+
+// rawAfter returns the first parsed line in the file that follows the given
+// order.
+func (c *Config) rawAfter(order int) *RawLineStyle {
+	var next *RawLineStyle
+	consider := func(raw *RawLineStyle) {
+		if raw.Order > order && raw.LineNumber > 0 && (next == nil || raw.Order < next.Order) {
+			next = raw
+		}
+	}
+	for _, group := range c.Groups {
+		for _, raw := range group.Raws {
+			consider(raw)
+		}
+		for _, entry := range group.Entries {
+			for _, raw := range entry.Raws {
+				consider(raw)
+			}
+		}
+	}
+	return next
+}
+
 type ConfigGroup struct {
 	Entries map[string]*ConfigEntry
 	Raws    []*RawLineStyle
@@ -41,6 +146,104 @@ func (g *ConfigGroup) CreateEntry(key string, value string) *ConfigEntry {
 	}
 }
 
+// DeleteEntry removes the entry for the key and reports whether it existed.
+//
+// Comments and blank lines attached to the removed lines are not dropped;
+// they are moved to the preceding line in the same group, or to the following
+// one if there is none, so that the rest of the file is rendered exactly as
+// before.
+func (g *ConfigGroup) DeleteEntry(key string) bool {
+	entry, ok := g.Entries[key]
+	if !ok {
+		return false
+	}
+	delete(g.Entries, key)
+	for _, raw := range entry.Raws {
+		if len(raw.LeadingComments) == 0 && len(raw.TrailingComments) == 0 {
+			continue
+		}
+		if prev := g.rawBefore(raw.Order); prev != nil {
+			prev.TrailingComments = append(prev.TrailingComments, raw.LeadingComments...)
+			prev.TrailingComments = append(prev.TrailingComments, raw.TrailingComments...)
+		} else if next := g.rawAfter(raw.Order); next != nil {
+			next.LeadingComments = slices.Concat(raw.LeadingComments, raw.TrailingComments, next.LeadingComments)
+		}
+	}
+	return true
+}
+
+// RenameEntry renames the key of the entry, keeping its value, position and
+// comments, and reports whether it existed. An existing entry for newKey is
+// deleted first, with its comments kept as described in DeleteEntry.
+func (g *ConfigGroup) RenameEntry(oldKey string, newKey string) bool {
+	entry, ok := g.Entries[oldKey]
+	if !ok {
+		return false
+	}
+	if oldKey == newKey {
+		return true
+	}
+	g.DeleteEntry(newKey)
+	delete(g.Entries, oldKey)
+	g.Entries[newKey] = entry
+	for _, raw := range entry.Raws {
+		raw.Line = renameKeyInLine(raw.Line, oldKey, newKey)
+	}
+	return true
+}
+
+// renameKeyInLine replaces the key of a key-value line, keeping the
+// surrounding whitespace.
+func renameKeyInLine(line string, oldKey string, newKey string) string {
+	eqPos := strings.IndexByte(line, '=')
+	if eqPos < 0 {
+		return line
+	}
+	keyPos := strings.Index(line[:eqPos], oldKey)
+	if keyPos < 0 {
+		return line
+	}
+	return line[:keyPos] + newKey + line[keyPos+len(oldKey):]
+}
+
+// rawBefore returns the last line in the group that precedes the given order.
+func (g *ConfigGroup) rawBefore(order int) *RawLineStyle {
+	var prev *RawLineStyle
+	consider := func(raw *RawLineStyle) {
+		if raw.Order < order && (prev == nil || raw.Order > prev.Order) {
+			prev = raw
+		}
+	}
+	for _, raw := range g.Raws {
+		consider(raw)
+	}
+	for _, entry := range g.Entries {
+		for _, raw := range entry.Raws {
+			consider(raw)
+		}
+	}
+	return prev
+}
+
+// rawAfter returns the first line in the group that follows the given order.
+func (g *ConfigGroup) rawAfter(order int) *RawLineStyle {
+	var next *RawLineStyle
+	consider := func(raw *RawLineStyle) {
+		if raw.Order > order && (next == nil || raw.Order < next.Order) {
+			next = raw
+		}
+	}
+	for _, raw := range g.Raws {
+		consider(raw)
+	}
+	for _, entry := range g.Entries {
+		for _, raw := range entry.Raws {
+			consider(raw)
+		}
+	}
+	return next
+}
+
 type ConfigEntry struct {
 	Value string
 	Raws  []*RawLineStyle
@@ -51,12 +254,22 @@ type RawLineStyle struct {
 	Line             string
 	LeadingComments  []string
 	TrailingComments []string
+	// 1-based line number in the parsed text; 0 for synthesized lines
+	LineNumber int
 }
 
 func ParseConfig(data string) *Config {
 	config := &Config{
 		Groups: map[string]*ConfigGroup{},
 	}
+	if strings.HasPrefix(data, utf8BOM) {
+		config.BOM = true
+		data = data[len(utf8BOM):]
+	}
+	if numLF, numCRLF := strings.Count(data, "\n"), strings.Count(data, "\r\n"); numCRLF > numLF-numCRLF {
+		config.LineEnding = "\r\n"
+	}
+	config.NoFinalNewline = data != "" && !strings.HasSuffix(data, "\n")
 	orderStep := 10
 	currentOrder := orderStep
 	var currentGroup *ConfigGroup
@@ -64,7 +277,9 @@ func ParseConfig(data string) *Config {
 	var pendingComments []string
 
 	pos := 0
+	lineNumber := 0
 	for pos < len(data) {
+		lineNumber++
 		lineEnd := strings.IndexByte(data[pos:], '\n')
 		if lineEnd < 0 {
 			lineEnd = len(data)
@@ -97,6 +312,7 @@ func ParseConfig(data string) *Config {
 			}
 			lastLine = &RawLineStyle{
 				Order:            currentOrder,
+				LineNumber:       lineNumber,
 				Line:             line,
 				LeadingComments:  pendingComments,
 				TrailingComments: nil,
@@ -122,10 +338,12 @@ func ParseConfig(data string) *Config {
 			}
 			lastLine = &RawLineStyle{
 				Order:            currentOrder,
+				LineNumber:       lineNumber,
 				Line:             line,
 				LeadingComments:  pendingComments,
 				TrailingComments: nil,
 			}
+			currentOrder += orderStep
 			pendingComments = nil
 			if entry, ok := currentGroup.Entries[key]; ok {
 				// Duplicate entry (not allowed spec-wise)
@@ -150,6 +368,7 @@ func ParseConfig(data string) *Config {
 }
 
 func (c *Config) String() string {
+	lineEnding := c.lineEnding()
 	type ConfigSortItem struct {
 		Order int
 		Name  string
@@ -173,7 +392,7 @@ func (c *Config) String() string {
 				Raw:   raw,
 			}
 			if parseGroupLine(entry.Line) != groupName {
-				entry.Line = "[" + groupName + "]\n"
+				entry.Line = "[" + groupName + "]" + lineEnding
 			}
 			groupItems = append(groupItems, entry)
 		}
@@ -181,7 +400,7 @@ func (c *Config) String() string {
 			groupItems = append(groupItems, &GroupSortEntry{
 				Order: c.EndRaw.Order,
 				Group: group,
-				Line:  "[" + groupName + "]\n",
+				Line:  "[" + groupName + "]" + lineEnding,
 			})
 		}
 		for entryKey, entry := range group.Entries {
@@ -189,7 +408,7 @@ func (c *Config) String() string {
 				groupItems = append(groupItems, &GroupSortEntry{
 					Order: c.EndRaw.Order,
 					Entry: entry,
-					Line:  entryKey + "=" + entry.Value + "\n",
+					Line:  entryKey + "=" + entry.Value + lineEnding,
 				})
 				continue
 			}
@@ -199,7 +418,7 @@ func (c *Config) String() string {
 				groupItems = append(groupItems, &GroupSortEntry{
 					Order: entry.Raws[0].Order,
 					Entry: entry,
-					Line:  entryKey + "=" + entry.Value + "\n",
+					Line:  entryKey + "=" + entry.Value + lineEnding,
 					Raw:   entry.Raws[0],
 				})
 				continue
@@ -293,7 +512,7 @@ func (c *Config) String() string {
 	for i, configItem := range configItems {
 		if i > 0 && len(configItem.Lines) > 0 && configItem.Lines[0] == "" {
 			// Instantiate dummy group header
-			configItem.Lines[0] = "[]\n"
+			configItem.Lines[0] = "[]" + lineEnding
 		}
 	}
 
@@ -306,10 +525,19 @@ func (c *Config) String() string {
 	}
 	for i, line := range lines {
 		if i < len(lines)-1 && !strings.HasSuffix(line, "\n") && line != "" {
-			lines[i] = line + "\n"
+			lines[i] = line + lineEnding
 		}
 	}
-	return strings.Join(lines, "")
+	text := strings.Join(lines, "")
+	if c.NoFinalNewline && strings.HasSuffix(text, lineEnding) {
+		text = strings.TrimSuffix(text, lineEnding)
+	} else if c.NoFinalNewline {
+		text = strings.TrimSuffix(text, "\n")
+	}
+	if c.BOM {
+		text = utf8BOM + text
+	}
+	return text
 }
 
 func parseGroupLine(line string) string {
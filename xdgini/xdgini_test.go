@@ -40,10 +40,34 @@ func TestParseStringifyRoundtrip(t *testing.T) {
 			name:  "with broken key-value pair",
 			input: "[Foo]\nBar\n",
 		},
+		{
+			name:  "with unsorted keys",
+			input: "[Foo]\nKey2=Value2\nKey1=Value1\n",
+		},
 		{
 			name:  "with trailing spaces",
 			input: "[Foo] \n Key1 = Value1 \n \n",
 		},
+		{
+			name:  "with CRLF",
+			input: "# Comment\r\n[Foo]\r\nKey1=Value1\r\n\r\n[Bar]\r\nKey2=Value2\r\n",
+		},
+		{
+			name:  "with mixed line endings",
+			input: "[Foo]\r\nKey1=Value1\nKey2=Value2\r\n",
+		},
+		{
+			name:  "with BOM",
+			input: "\ufeff[Foo]\nKey1=Value1\n",
+		},
+		{
+			name:  "with BOM and CRLF without last newline",
+			input: "\ufeff[Foo]\r\nKey1=Value1",
+		},
+		{
+			name:  "with CR at the end",
+			input: "[Foo]\nKey1=Value1\r",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -56,3 +80,261 @@ func TestParseStringifyRoundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestLineEndingStyle(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			name:   "LF",
+			input:  "[Foo]\nKey1=Value1\n",
+			output: "[Foo]\nKey1=Value1\nKey2=Value2\n[Bar]\nKey3=Value3\n",
+		},
+		{
+			name:   "CRLF",
+			input:  "[Foo]\r\nKey1=Value1\r\n",
+			output: "[Foo]\r\nKey1=Value1\r\nKey2=Value2\r\n[Bar]\r\nKey3=Value3\r\n",
+		},
+		{
+			name:   "mostly CRLF",
+			input:  "[Foo]\r\nKey1=Value1\n\r\n",
+			output: "[Foo]\r\nKey1=Value1\n\r\nKey2=Value2\r\n[Bar]\r\nKey3=Value3\r\n",
+		},
+		{
+			name:   "BOM",
+			input:  "\ufeff[Foo]\nKey1=Value1\n",
+			output: "\ufeff[Foo]\nKey1=Value1\nKey2=Value2\n[Bar]\nKey3=Value3\n",
+		},
+		{
+			name:   "without last newline",
+			input:  "[Foo]\r\nKey1=Value1",
+			output: "[Foo]\r\nKey1=Value1\r\nKey2=Value2\r\n[Bar]\r\nKey3=Value3",
+		},
+		{
+			name:   "empty",
+			input:  "",
+			output: "[Bar]\nKey3=Value3\n[Foo]\nKey2=Value2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			config.CreateGroup("Foo").CreateEntry("Key2", "Value2")
+			config.CreateGroup("Bar").CreateEntry("Key3", "Value3")
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// BOM does not break the first group header
+	config := xdgini.ParseConfig("\ufeff[Foo]\r\nKey1=Value1\r\n")
+	if _, ok := config.Groups["Foo"]; !ok {
+		t.Errorf("group Foo not found after BOM")
+	}
+}
+
+func TestDeleteEntry(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		group  string
+		key    string
+		output string
+	}{
+		{
+			name:   "simple",
+			input:  "[Foo]\nKey1=Value1\nKey2=Value2\nKey3=Value3\n",
+			group:  "Foo",
+			key:    "Key2",
+			output: "[Foo]\nKey1=Value1\nKey3=Value3\n",
+		},
+		{
+			name:   "keeps comments",
+			input:  "[Foo]\n# Comment 1\nKey1=Value1\n\n# Comment 2\nKey2=Value2\n\n[Bar]\nKey3=Value3\n",
+			group:  "Foo",
+			key:    "Key2",
+			output: "[Foo]\n# Comment 1\nKey1=Value1\n\n# Comment 2\n\n[Bar]\nKey3=Value3\n",
+		},
+		{
+			name:   "first entry",
+			input:  "[Foo]\n\n# Comment 1\nKey1=Value1\nKey2=Value2\n",
+			group:  "Foo",
+			key:    "Key1",
+			output: "[Foo]\n\n# Comment 1\nKey2=Value2\n",
+		},
+		{
+			name:   "duplicate entries",
+			input:  "[Foo]\nKey1=Value1\n# Comment 1\nKey2=Value2\nKey1=Value3\n# Comment 2\n",
+			group:  "Foo",
+			key:    "Key1",
+			output: "[Foo]\n# Comment 1\nKey2=Value2\n# Comment 2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			if !config.Groups[tc.group].DeleteEntry(tc.key) {
+				t.Fatalf("DeleteEntry(%q) = false, want true", tc.key)
+			}
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeleteEntryWithoutPrecedingLine(t *testing.T) {
+	// Like a group created without a header
+	config := &xdgini.Config{
+		Groups: map[string]*xdgini.ConfigGroup{
+			"Foo": {
+				Entries: map[string]*xdgini.ConfigEntry{
+					"Key1": {
+						Value: "Value1",
+						Raws:  []*xdgini.RawLineStyle{{Order: 10, Line: "Key1=Value1\n", LeadingComments: []string{"# Comment 1\n"}}},
+					},
+					"Key2": {
+						Value: "Value2",
+						Raws:  []*xdgini.RawLineStyle{{Order: 20, Line: "Key2=Value2\n", LeadingComments: []string{"# Comment 2\n"}}},
+					},
+				},
+			},
+		},
+		EndRaw: &xdgini.RawLineStyle{Order: 30},
+	}
+	if !config.Groups["Foo"].DeleteEntry("Key1") {
+		t.Fatal("DeleteEntry(Key1) = false, want true")
+	}
+	if diff := cmp.Diff("[Foo]\n# Comment 1\n# Comment 2\nKey2=Value2\n", config.String()); diff != "" {
+		t.Errorf("String() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		group  string
+		output string
+	}{
+		{
+			name:   "simple",
+			input:  "[Foo]\nKey1=Value1\n[Bar]\nKey2=Value2\n[Baz]\nKey3=Value3\n",
+			group:  "Bar",
+			output: "[Foo]\nKey1=Value1\n[Baz]\nKey3=Value3\n",
+		},
+		{
+			name:   "keeps comments",
+			input:  "[Foo]\nKey1=Value1\n\n# Comment 1\n\n[Bar]\n# Comment 2\nKey2=Value2\n\n[Baz]\nKey3=Value3\n",
+			group:  "Bar",
+			output: "[Foo]\nKey1=Value1\n\n# Comment 1\n\n# Comment 2\n\n[Baz]\nKey3=Value3\n",
+		},
+		{
+			name:   "removes comments on the header",
+			input:  "[Foo]\nKey1=Value1\n\n# About Bar\n[Bar]\nKey2=Value2\n\n[Baz]\nKey3=Value3\n",
+			group:  "Bar",
+			output: "[Foo]\nKey1=Value1\n\n\n[Baz]\nKey3=Value3\n",
+		},
+		{
+			name:   "removes comments on the header of the only group",
+			input:  "# About Foo\n[Foo]\nKey1=Value1\n",
+			group:  "Foo",
+			output: "",
+		},
+		{
+			name:   "first group",
+			input:  "# Comment 1\n\n[Foo]\nKey1=Value1\n# Comment 2\n[Bar]\nKey2=Value2\n",
+			group:  "Foo",
+			output: "# Comment 1\n\n# Comment 2\n[Bar]\nKey2=Value2\n",
+		},
+		{
+			name:   "only group",
+			input:  "# Comment 1\n\n[Foo]\nKey1=Value1\n# Comment 2\n",
+			group:  "Foo",
+			output: "# Comment 1\n\n# Comment 2\n",
+		},
+		{
+			name:   "duplicate groups",
+			input:  "[Foo]\nKey1=Value1\n[Bar]\nKey2=Value2\n[Foo]\nKey3=Value3\n",
+			group:  "Foo",
+			output: "[Bar]\nKey2=Value2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			if !config.DeleteGroup(tc.group) {
+				t.Fatalf("DeleteGroup(%q) = false, want true", tc.group)
+			}
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+			if config.DeleteGroup(tc.group) {
+				t.Errorf("DeleteGroup(%q) = true for a deleted group", tc.group)
+			}
+		})
+	}
+}
+
+func TestRenameEntry(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		oldKey string
+		newKey string
+		value  *string
+		output string
+	}{
+		{
+			name:   "keeps position and spacing",
+			input:  "[Foo]\nKey1=Value1\n# Comment\n  Key2 = Value2\nKey3=Value3\n",
+			oldKey: "Key2",
+			newKey: "Key4",
+			output: "[Foo]\nKey1=Value1\n# Comment\n  Key4 = Value2\nKey3=Value3\n",
+		},
+		{
+			name:   "with new value",
+			input:  "[Foo]\nKey1=Value1\nKey2=Value2\n",
+			oldKey: "Key1",
+			newKey: "Key3",
+			value:  ptr("Value3"),
+			output: "[Foo]\nKey3=Value3\nKey2=Value2\n",
+		},
+		{
+			name:   "replaces existing key",
+			input:  "[Foo]\nKey1=Value1\n# Comment\nKey2=Value2\n",
+			oldKey: "Key2",
+			newKey: "Key1",
+			output: "[Foo]\n# Comment\nKey1=Value2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := xdgini.ParseConfig(tc.input)
+			group := config.Groups["Foo"]
+			if !group.RenameEntry(tc.oldKey, tc.newKey) {
+				t.Fatalf("RenameEntry(%q, %q) = false, want true", tc.oldKey, tc.newKey)
+			}
+			if tc.value != nil {
+				group.CreateEntry(tc.newKey, *tc.value)
+			}
+			if diff := cmp.Diff(tc.output, config.String()); diff != "" {
+				t.Errorf("String() mismatch (-want +got):\n%s", diff)
+			}
+			if group.RenameEntry(tc.oldKey, "Other") {
+				t.Errorf("RenameEntry(%q) = true for a renamed key", tc.oldKey)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}